@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// parseAnnotatedClass reads the class out of an annotation value, either a
+// class name in any case or a cgroup path, e.g.
+// kubepods/burstable/pod<uid> or kubepods.slice/kubepods-pod<uid>.slice.
+// Guaranteed pods sit right under kubepods, the other classes get a
+// directory of their own
+func parseAnnotatedClass(value string) (podqos.PodQosPolicy, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for class := range classRank {
+		if value == strings.ToLower(string(class)) {
+			return class, true
+		}
+	}
+	switch {
+	case strings.Contains(value, "besteffort"):
+		return podqos.BestEffort, true
+	case strings.Contains(value, "burstable"):
+		return podqos.Burstable, true
+	case strings.Contains(value, "kubepods"):
+		return podqos.Guaranteed, true
+	}
+	return "", false
+}
+
+// useAnnotatedClasses sets the class of every container of the pods that
+// carry the annotation, a pod's containers all run in the cgroup of its
+// class. Pods without it, or with a value that isn't a class, keep the
+// computed class
+func useAnnotatedClasses(podData []podqos.PodData, key string) {
+	for i := range podData {
+		value, ok := podData[i].Annotations[key]
+		if !ok {
+			continue
+		}
+		class, ok := parseAnnotatedClass(value)
+		if !ok {
+			logger.warnf("pod %s/%s: annotation %s=%q is not a class, using the computed one", podData[i].NameSpace, podData[i].PodName, key, value)
+			continue
+		}
+		for j := range podData[i].Containers {
+			podData[i].Containers[j].Class = class
+		}
+		for j := range podData[i].InitContainers {
+			podData[i].InitContainers[j].Class = class
+		}
+	}
+}
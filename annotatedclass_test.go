@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseAnnotatedClass(t *testing.T) {
+	for value, want := range map[string]podqos.PodQosPolicy{
+		"Guaranteed":                  podqos.Guaranteed,
+		" burstable":                  podqos.Burstable,
+		"BESTEFFORT":                  podqos.BestEffort,
+		"kubepods/burstable/pod1234":  podqos.Burstable,
+		"kubepods/besteffort/pod1234": podqos.BestEffort,
+		"kubepods/pod1234":            podqos.Guaranteed,
+		"kubepods.slice/kubepods-besteffort.slice/x.slice": podqos.BestEffort,
+		"kubepods.slice/kubepods-pod1234.slice":            podqos.Guaranteed,
+	} {
+		if got, ok := parseAnnotatedClass(value); !ok || got != want {
+			t.Errorf("parseAnnotatedClass(%q) = %s %v, want %s", value, got, ok, want)
+		}
+	}
+	for _, value := range []string{"", "premium", "/sys/fs/cgroup/system.slice"} {
+		if got, ok := parseAnnotatedClass(value); ok {
+			t.Errorf("parseAnnotatedClass(%q) = %s, want no class", value, got)
+		}
+	}
+}
+
+func TestUseAnnotatedClasses(t *testing.T) {
+	log := captureLog(t)
+	const key = "example.com/qos"
+	annotated := func(pod *v1.Pod, value string) *v1.Pod {
+		pod.Annotations = map[string]string{key: value}
+		return pod
+	}
+	web := annotated(newPod("default", "web", guaranteedContainer("app"), guaranteedContainer("sidecar")), "kubepods/besteffort/pod1")
+	web.Spec.InitContainers = []v1.Container{guaranteedContainer("migrate")}
+	// the annotation of --kubectl-compat isn't the key asked for
+	other := newPod("default", "other", guaranteedContainer("app"))
+	other.Annotations = map[string]string{classAnnotation: "BestEffort"}
+	podData := toPodData(
+		web,
+		annotated(newPod("default", "db", bestEffortContainer("pg")), "Burstable"),
+		annotated(newPod("default", "broken", guaranteedContainer("app")), "premium"),
+		newPod("default", "plain", burstableContainer("app")),
+		other,
+	)
+	useAnnotatedClasses(podData, key)
+
+	want := []podqos.PodQosPolicy{podqos.BestEffort, podqos.Burstable, podqos.Guaranteed, podqos.Burstable, podqos.Guaranteed}
+	for i, p := range podData {
+		if got := p.QosClass(cpuMemory); got != want[i] {
+			t.Errorf("%s = %s, want %s", p.PodName, got, want[i])
+		}
+	}
+	// every container of the pod runs in the annotated class
+	for _, c := range append(podData[0].Containers, podData[0].InitContainers...) {
+		if got := c.QosClass(cpuMemory); got != podqos.BestEffort {
+			t.Errorf("web/%s = %s, want BestEffort", c.Name, got)
+		}
+	}
+	if !strings.Contains(log.String(), `pod default/broken: annotation example.com/qos="premium" is not a class`) {
+		t.Errorf("log = %q, want a warning for broken", log.String())
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// ChangeType says how a container differs from the baseline
+type ChangeType string
+
+const (
+	// Added containers are new since the baseline
+	Added ChangeType = "ADDED"
+
+	// Removed containers were in the baseline but are gone now
+	Removed ChangeType = "REMOVED"
+
+	// Changed containers have a different class than in the baseline
+	Changed ChangeType = "CHANGED"
+)
+
+// ReportChange is a single container that differs from the baseline
+type ReportChange struct {
+	Type      ChangeType
+	Context   string
+	Namespace string
+	Pod       string
+	Container string
+	Before    podqos.PodQosPolicy
+	After     podqos.PodQosPolicy
+}
+
+// loadReport reads a report previously written with -o json, a list of pods
+// in either version or a single pod. Anything else is rejected rather than
+// read as an empty report, which would show every container as added
+func loadReport(path string) (Report, error) {
+	var report Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	switch {
+	case report.APIVersion == reportGroup+"/v1":
+		// a v1 report keeps its pods under items, convert it back
+		var v1Report ReportV1
+		if err := json.Unmarshal(data, &v1Report); err != nil {
+			return report, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+		}
+		if v1Report.Items == nil {
+			return report, fmt.Errorf("baseline %s has no items", path)
+		}
+		return reportFromV1(v1Report), nil
+	case report.Kind == podReportKind:
+		var pod SinglePodReport
+		if err := json.Unmarshal(data, &pod); err != nil {
+			return report, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+		}
+		return Report{APIVersion: pod.APIVersion, Kind: reportKind, Pods: []PodReport{pod.PodReport}}, nil
+	case report.Pods == nil:
+		return report, fmt.Errorf("baseline %s has no pods, save it with -o json", path)
+	}
+	return report, nil
+}
+
+// reportClasses indexes the class of every container in the report
+func reportClasses(report Report) map[[4]string]podqos.PodQosPolicy {
+	classes := map[[4]string]podqos.PodQosPolicy{}
+	for _, p := range report.Pods {
+		for _, c := range p.Containers {
+			classes[[4]string{p.Context, p.Namespace, p.Name, c.Name}] = c.Class
+		}
+	}
+	return classes
+}
+
+// diffReports compares the current report to the baseline container by
+// container, sorted by context, namespace, pod and container
+func diffReports(baseline, current Report) []ReportChange {
+	before := reportClasses(baseline)
+	after := reportClasses(current)
+
+	var changes []ReportChange
+	for key, class := range after {
+		change := ReportChange{Context: key[0], Namespace: key[1], Pod: key[2], Container: key[3], After: class}
+		old, ok := before[key]
+		switch {
+		case !ok:
+			change.Type = Added
+		case old != class:
+			change.Type = Changed
+			change.Before = old
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for key, class := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, ReportChange{Type: Removed, Context: key[0], Namespace: key[1], Pod: key[2], Container: key[3], Before: class})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a := []string{changes[i].Context, changes[i].Namespace, changes[i].Pod, changes[i].Container}
+		b := []string{changes[j].Context, changes[j].Namespace, changes[j].Pod, changes[j].Container}
+		return strings.Join(a, "\x00") < strings.Join(b, "\x00")
+	})
+	return changes
+}
+
+// printChanges writes one row per changed container
+func printChanges(w io.Writer, changes []ReportChange, showContext bool) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no changes since baseline")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"CHANGE", "NAMESPACE", "POD NAME", "CONTAINER", "BEFORE", "AFTER"}
+	if showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, c := range changes {
+		row := []string{string(c.Type), c.Namespace, c.Pod, c.Container, noneIfEmpty(string(c.Before)), noneIfEmpty(string(c.After))}
+		if showContext {
+			row = append([]string{c.Context}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+func TestDiffReports(t *testing.T) {
+	before := newReport(toPodData(
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "gone", bestEffortContainer("app"))), cpuMemory)
+	after := newReport(toPodData(
+		newPod("default", "web", guaranteedContainer("app"), bestEffortContainer("sidecar")),
+		newPod("default", "new", bestEffortContainer("app"))), cpuMemory)
+
+	changes := diffReports(before, after)
+	want := []ReportChange{
+		{Type: Removed, Namespace: "default", Pod: "gone", Container: "app", Before: podqos.BestEffort},
+		{Type: Added, Namespace: "default", Pod: "new", Container: "app", After: podqos.BestEffort},
+		{Type: Changed, Namespace: "default", Pod: "web", Container: "app", Before: podqos.Burstable, After: podqos.Guaranteed},
+		{Type: Added, Namespace: "default", Pod: "web", Container: "sidecar", After: podqos.BestEffort},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("diffReports = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	printChanges(&buf, changes, false)
+	if got := strings.Join(strings.Fields(strings.Split(buf.String(), "\n")[1]), " "); got != "REMOVED default gone app BestEffort <none>" {
+		t.Errorf("first row = %q", got)
+	}
+	buf.Reset()
+	printChanges(&buf, diffReports(after, after), false)
+	if buf.String() != "no changes since baseline\n" {
+		t.Errorf("no changes printed %q", buf.String())
+	}
+}
+
+func TestDiffAgainstSavedBaseline(t *testing.T) {
+	podData := toPodData(newPod("default", "web", burstableContainer("app")))
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	content, err := json.MarshalIndent(newReport(podData, cpuMemory), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := loadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := diffReports(baseline, newReport(podData, cpuMemory)); len(changes) != 0 {
+		t.Errorf("diff of a report against itself = %+v, want none", changes)
+	}
+}
+
+func TestLoadReportRejectsFilesWithoutPods(t *testing.T) {
+	for _, content := range []string{`{}`, `{"name": "web", "containers": []}`, `{"apiVersion": "podqos.jdambly.github.io/v1"}`} {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadReport(path); err == nil {
+			t.Errorf("loadReport(%s) = nil error, want one", content)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"sync"
+)
+
+// objectCache remembers api lookups for the length of a run so pods sharing
+// an owner only cost one Get, it is safe for concurrent use. Concurrent
+// lookups of the same object wait for the one fetch in flight
+type objectCache struct {
+	mu      sync.Mutex
+	objects map[string]*cacheEntry
+}
+
+// cacheEntry is a fetched object, done is closed once obj and err are set
+type cacheEntry struct {
+	done chan struct{}
+	obj  interface{}
+	err  error
+}
+
+func newObjectCache() *objectCache {
+	return &objectCache{objects: map[string]*cacheEntry{}}
+}
+
+// get returns the cached object for namespace/kind/name or calls fetch and
+// caches its result, errors are not cached so a later lookup retries
+func (c *objectCache) get(namespace, kind, name string, fetch func() (interface{}, error)) (interface{}, error) {
+	key := namespace + "/" + kind + "/" + name
+	c.mu.Lock()
+	entry, ok := c.objects[key]
+	if ok {
+		c.mu.Unlock()
+		<-entry.done
+		return entry.obj, entry.err
+	}
+	entry = &cacheEntry{done: make(chan struct{})}
+	c.objects[key] = entry
+	c.mu.Unlock()
+
+	entry.obj, entry.err = fetch()
+	if entry.err != nil {
+		entry.obj = nil
+		c.mu.Lock()
+		delete(c.objects, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.obj, entry.err
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestObjectCacheFetchesOnce(t *testing.T) {
+	cache := newObjectCache()
+	var fetches int64
+	fetch := func() (interface{}, error) {
+		atomic.AddInt64(&fetches, 1)
+		// keep the fetch in flight while the others look it up
+		time.Sleep(10 * time.Millisecond)
+		return "rs", nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if obj, err := cache.get("default", "ReplicaSet", "web", fetch); obj != "rs" || err != nil {
+				t.Errorf("get = %v %v", obj, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want once", fetches)
+	}
+}
+
+func TestObjectCacheRetriesErrors(t *testing.T) {
+	cache := newObjectCache()
+	if _, err := cache.get("default", "ReplicaSet", "web", func() (interface{}, error) { return nil, errors.New("timeout") }); err == nil {
+		t.Fatal("get = nil error, want the fetch error")
+	}
+	obj, err := cache.get("default", "ReplicaSet", "web", func() (interface{}, error) { return "rs", nil })
+	if obj != "rs" || err != nil {
+		t.Errorf("get after an error = %v %v, want a new fetch", obj, err)
+	}
+}
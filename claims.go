@@ -0,0 +1,55 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// claimsSpec finds the spec of the app or init container the claims are
+// read from, nil when it isn't known
+func claimsSpec(p podqos.PodData, name string) *v1.Container {
+	if spec := containerSpec(p, name); spec != nil || p.Pod == nil {
+		return spec
+	}
+	for i := range p.Pod.Spec.InitContainers {
+		if p.Pod.Spec.InitContainers[i].Name == name {
+			return &p.Pod.Spec.InitContainers[i]
+		}
+	}
+	return nil
+}
+
+// claimsCell lists the resource claims of the container, e.g. gpu or
+// gpu/big when it asks for a single request of the claim. Devices allocated
+// through a claim don't show up in the limits and requests, so a container
+// can claim a gpu and still be BestEffort
+func claimsCell(p podqos.PodData, name string) string {
+	spec := claimsSpec(p, name)
+	if spec == nil || len(spec.Resources.Claims) == 0 {
+		return "<none>"
+	}
+	var claims []string
+	for _, claim := range spec.Resources.Claims {
+		if claim.Request != "" {
+			claims = append(claims, claim.Name+"/"+claim.Request)
+		} else {
+			claims = append(claims, claim.Name)
+		}
+	}
+	return strings.Join(claims, ",")
+}
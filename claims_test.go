@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestClaimsColumn(t *testing.T) {
+	gpu := bestEffortContainer("trainer")
+	gpu.Resources.Claims = []v1.ResourceClaim{{Name: "gpu"}, {Name: "nic", Request: "fast"}}
+	pod := newPod("ml", "train", gpu, bestEffortContainer("sidecar"))
+	pod.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "gpu"}, {Name: "nic"}}
+	podData := toPodData(pod)
+
+	if got := claimsCell(podData[0], "trainer"); got != "gpu,nic/fast" {
+		t.Errorf("claimsCell(trainer) = %q, want gpu,nic/fast", got)
+	}
+	if got := claimsCell(podData[0], "sidecar"); got != "<none>" {
+		t.Errorf("claimsCell(sidecar) = %q, want <none>", got)
+	}
+
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{showClaims: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasSuffix(lines[0], "CLAIMS") {
+		t.Errorf("header = %q, want a CLAIMS column", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "gpu,nic/fast") || !strings.HasSuffix(lines[2], "<none>") {
+		t.Errorf("rows = %q, want the claims of each container", lines[1:])
+	}
+
+	// manifests without a pod object show no claims either
+	if got := claimsCell(podData[0], "missing"); got != "<none>" {
+		t.Errorf("claimsCell(missing) = %q, want <none>", got)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// version, commit and date are set by goreleaser at build time
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// subcommands are the commands the first argument can name, anything else
+// is handed to list so `kubectl podqos -n <namespace>` and `kubectl podqos
+// <pod>` keep working
+var subcommands = []string{"list", "get", "explain", "version"}
+
+// usage is printed above the flags of list and get
+const usage = `Usage:
+  kubectl podqos [list] [flags] [pod]  show the class of every container, or of a single pod
+  kubectl podqos get <pod> [flags]     show the class of a single pod
+  kubectl podqos explain <class>       explain how a class is assigned and when it is evicted
+  kubectl podqos version               print the version
+
+Flags of list and get:
+`
+
+func main() {
+	command, args := parseCommand(os.Args[1:])
+	switch command {
+	case "version":
+		printVersion(os.Stdout)
+	case "explain":
+		if err := runExplain(os.Stdout, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		runList(args, command == "get")
+	}
+}
+
+// parseCommand splits the subcommand off the arguments, list when none is
+// named. flag stops at the first argument that isn't a flag, so a leading
+// pod name is moved to the end for the flags after it, as in
+// get <pod> -n <namespace>, to still be parsed
+func parseCommand(args []string) (string, []string) {
+	command := "list"
+	if len(args) > 0 && validSortKey(args[0], subcommands) {
+		command, args = args[0], args[1:]
+	}
+	if len(args) > 1 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	return command, args
+}
+
+// printVersion writes the build version
+func printVersion(w io.Writer) {
+	fmt.Fprintf(w, "kubectl-podqos %s (commit %s, built %s)\n", version, commit, date)
+}
+
+// classRules say how a pod ends up in each class
+var classRules = map[podqos.PodQosPolicy]string{
+	podqos.BestEffort: "no container sets a request or a limit",
+	podqos.Burstable:  "some container sets a request or a limit, but not every container has limits with requests equal to them",
+	podqos.Guaranteed: "every container sets limits, with requests equal to them or left out so they default to the limits",
+}
+
+// runExplain explains a single class, given by name or its short letter
+func runExplain(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: kubectl podqos explain <Guaranteed|Burstable|BestEffort>")
+	}
+	for class := range classRank {
+		if strings.EqualFold(args[0], string(class)) || strings.EqualFold(args[0], shortClasses[class]) {
+			fmt.Fprintf(w, "%s\n  assigned: %s\n  eviction: %s\n", class, classRules[class], evictionExplanations[class])
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown class %q, use Guaranteed, Burstable or BestEffort", args[0])
+}
+
+// setUsage prints the subcommands above the flags on -h
+func setUsage(flags *flag.FlagSet) {
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs main instead of the tests when PODQOS_TEST_MAIN is set, so
+// runPodqos can run the binary the way kubectl runs the plugin
+func TestMain(m *testing.M) {
+	if os.Getenv("PODQOS_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runPodqos runs the test binary as kubectl-podqos with args and returns its
+// stdout
+func runPodqos(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "PODQOS_TEST_MAIN=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("kubectl-podqos %s: %v: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return string(out)
+}
+
+func TestParseCommand(t *testing.T) {
+	for _, test := range []struct {
+		args    []string
+		command string
+		rest    []string
+	}{
+		{nil, "list", nil},
+		{[]string{"-n", "shop"}, "list", []string{"-n", "shop"}},
+		{[]string{"list", "-A"}, "list", []string{"-A"}},
+		{[]string{"web", "-n", "shop"}, "list", []string{"-n", "shop", "web"}},
+		{[]string{"get", "web", "-n", "shop"}, "get", []string{"-n", "shop", "web"}},
+		{[]string{"get", "-n", "shop", "web"}, "get", []string{"-n", "shop", "web"}},
+		{[]string{"explain", "Burstable"}, "explain", []string{"Burstable"}},
+		{[]string{"version"}, "version", []string{}},
+	} {
+		command, rest := parseCommand(test.args)
+		if command != test.command || strings.Join(rest, " ") != strings.Join(test.rest, " ") {
+			t.Errorf("parseCommand(%q) = %s %q, want %s %q", test.args, command, rest, test.command, test.rest)
+		}
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	var buf bytes.Buffer
+	printVersion(&buf)
+	if want := "kubectl-podqos " + version + " (commit " + commit + ", built " + date + ")\n"; buf.String() != want {
+		t.Errorf("printVersion = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunExplain(t *testing.T) {
+	for _, class := range []string{"Guaranteed", "burstable", "b", "E"} {
+		var buf bytes.Buffer
+		if err := runExplain(&buf, []string{class}); err != nil {
+			t.Errorf("runExplain(%s): %v", class, err)
+			continue
+		}
+		if !strings.Contains(buf.String(), "  assigned: ") || !strings.Contains(buf.String(), "  eviction: ") {
+			t.Errorf("runExplain(%s) = %q, want the assignment and eviction rules", class, buf.String())
+		}
+	}
+	var buf bytes.Buffer
+	if runExplain(&buf, []string{"b"}); !strings.HasPrefix(buf.String(), "Burstable\n") {
+		t.Errorf("runExplain(b) = %q, want Burstable", buf.String())
+	}
+	if err := runExplain(&buf, []string{"Premium"}); err == nil || !strings.Contains(err.Error(), `unknown class "Premium"`) {
+		t.Errorf("runExplain(Premium) = %v, want an unknown class error", err)
+	}
+	if err := runExplain(&buf, nil); err == nil {
+		t.Error("runExplain without a class succeeded")
+	}
+}
+
+func TestSubcommands(t *testing.T) {
+	server := newAPIServer(t,
+		newPod("shop", "web", guaranteedContainer("app")),
+		newPod("shop", "worker", burstableContainer("app")),
+	)
+	cluster := []string{"--server", server.URL, "-n", "shop"}
+
+	list := runPodqos(t, append([]string{"list"}, cluster...)...)
+	if !strings.Contains(list, "web") || !strings.Contains(list, "worker") {
+		t.Errorf("list = %q, want both pods", list)
+	}
+	if bare := runPodqos(t, cluster...); bare != list {
+		t.Errorf("without a subcommand = %q, want the list %q", bare, list)
+	}
+
+	// the pod name may come before the flags
+	get := runPodqos(t, append([]string{"get", "worker"}, cluster...)...)
+	if !strings.Contains(get, "worker") || strings.Contains(get, "web") {
+		t.Errorf("get worker = %q, want only worker", get)
+	}
+
+	if explain := runPodqos(t, "explain", "BestEffort"); !strings.HasPrefix(explain, "BestEffort\n") {
+		t.Errorf("explain = %q", explain)
+	}
+	if got := runPodqos(t, "version"); !strings.HasPrefix(got, "kubectl-podqos ") {
+		t.Errorf("version = %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// namespaceSection is the header line of the pod's namespace, prefixed with
+// its context when contexts are shown
+func namespaceSection(p podqos.PodData, opts tableOptions) string {
+	if opts.showContext {
+		return p.Context + "/" + displayName(p.NameSpace, opts)
+	}
+	return displayName(p.NameSpace, opts)
+}
+
+// bySection groups the pods of each section together, in the order the
+// sections first appear, keeping the sorted order within a section
+func bySection(podData []podqos.PodData, opts tableOptions) []podqos.PodData {
+	var order []string
+	sections := map[string][]podqos.PodData{}
+	for _, p := range podData {
+		key := namespaceSection(p, opts)
+		if _, ok := sections[key]; !ok {
+			order = append(order, key)
+		}
+		sections[key] = append(sections[key], p)
+	}
+	grouped := make([]podqos.PodData, 0, len(podData))
+	for _, key := range order {
+		grouped = append(grouped, sections[key]...)
+	}
+	return grouped
+}
+
+// printNamespaceSections writes each namespace once as a header line with
+// the rows of its pods beneath it, without the NAMESPACE column
+func printNamespaceSections(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	podData = bySection(podData, opts)
+
+	// align every row as one table first, then put the namespace lines in
+	// between so they don't break up the columns
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(append([]string{"POD NAME"}, containerHeader(opts)...), "\t"))
+	for _, v := range podData {
+		for _, c := range v.Containers {
+			fmt.Fprintln(tw, strings.Join(append([]string{displayName(v.PodName, opts)}, containerCells(v, c, opts)...), "\t"))
+		}
+	}
+	tw.Flush()
+	lines := strings.Split(buf.String(), "\n")
+
+	if !opts.noHeaders {
+		fmt.Fprintln(w, "  "+lines[0])
+	}
+	next := 1
+	section := ""
+	for i, v := range podData {
+		if key := namespaceSection(v, opts); i == 0 || key != section {
+			fmt.Fprintln(w, key)
+			section = key
+		}
+		for range v.Containers {
+			fmt.Fprintln(w, "  "+lines[next])
+			next++
+		}
+	}
+}
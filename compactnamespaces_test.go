@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintNamespaceSections(t *testing.T) {
+	// sorted by size the namespaces are interleaved
+	podData := toPodData(
+		newPod("shop", "web", guaranteedContainer("app"), bestEffortContainer("sidecar")),
+		newPod("blog", "wp", burstableContainer("php")),
+		newPod("shop", "db", guaranteedContainer("pg")),
+	)
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{compactNamespaces: true})
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		"POD NAME CONTAINER CPUl CPUr CLASS",
+		"shop",
+		"web app 1 1 Guaranteed",
+		"web sidecar <none> <none> BestEffort",
+		"db pg 1 1 Guaranteed",
+		"blog",
+		"wp php 1 250m Burstable",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("sections = %q", lines)
+	}
+	for i, line := range lines {
+		if got := strings.Join(strings.Fields(line), " "); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+	// the rows are indented under their section
+	if !strings.HasPrefix(lines[2], "  web ") {
+		t.Errorf("row = %q, want it indented", lines[2])
+	}
+
+	// with contexts the same namespace in two clusters is two sections
+	podData[0].Context, podData[1].Context, podData[2].Context = "prod", "prod", "dev"
+	buf.Reset()
+	printTable(&buf, podData, tableOptions{compactNamespaces: true, showContext: true, noHeaders: true})
+	var sections []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasPrefix(line, " ") {
+			sections = append(sections, line)
+		}
+	}
+	if got := strings.Join(sections, " "); got != "prod/shop prod/blog dev/shop" {
+		t.Errorf("sections = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerFields are the computed fields --container-selector matches on,
+// the limits and requests count as set when every resource the class is
+// computed from is set
+var containerFields = map[string]func(c podqos.ContainerData, resources []v1.ResourceName) string{
+	"name": func(c podqos.ContainerData, resources []v1.ResourceName) string {
+		return c.Name
+	},
+	"class": func(c podqos.ContainerData, resources []v1.ResourceName) string {
+		return string(c.QosClass(resources))
+	},
+	"hasLimit": func(c podqos.ContainerData, resources []v1.ResourceName) string {
+		return strconv.FormatBool(hasAll(c.Limits, resources))
+	},
+	"hasRequest": func(c podqos.ContainerData, resources []v1.ResourceName) string {
+		return strconv.FormatBool(hasAll(c.Requests, resources))
+	},
+}
+
+// hasAll reports whether every resource is set
+func hasAll(r podqos.ResourceData, resources []v1.ResourceName) bool {
+	for _, name := range resources {
+		if !r.Has(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseContainerSelector parses a --container-selector value, comma
+// separated key=value or key!=value terms like a label selector, e.g.
+// class=Guaranteed,hasLimit=true. Values are compared case insensitively
+func parseContainerSelector(value string) ([]containerPredicate, error) {
+	var predicates []containerPredicate
+	for _, term := range splitList(value) {
+		op := "="
+		if strings.Contains(term, "!=") {
+			op = "!="
+		}
+		parts := strings.SplitN(term, op, 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid --container-selector term %q, expected key=value or key!=value", term)
+		}
+		key, want := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		field, ok := containerFields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown --container-selector key %q, use one of: class, hasLimit, hasRequest, name", key)
+		}
+		negate := op == "!="
+		predicates = append(predicates, func(c podqos.ContainerData, resources []v1.ResourceName) bool {
+			return strings.EqualFold(field(c, resources), want) != negate
+		})
+	}
+	return predicates, nil
+}
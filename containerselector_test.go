@@ -0,0 +1,46 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+)
+
+func TestContainerSelector(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app"), guaranteedContainer("proxy")),
+		newPod("default", "batch", bestEffortContainer("job")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	for value, want := range map[string]string{
+		"class=Guaranteed":                "web/proxy db/pg",
+		"class=guaranteed":                "web/proxy db/pg",
+		"class!=Guaranteed":               "web/app batch/job",
+		"hasLimit=true":                   "web/app web/proxy db/pg",
+		"hasLimit=true,class!=Guaranteed": "web/app",
+		"name=job, hasRequest=false":      "batch/job",
+	} {
+		predicates, err := parseContainerSelector(value)
+		if err != nil {
+			t.Fatalf("parseContainerSelector(%q) = %v", value, err)
+		}
+		if got := containerNames(filterContainers(podData, predicates, cpuMemory)); got != want {
+			t.Errorf("--container-selector %s kept %q, want %q", value, got, want)
+		}
+	}
+	for _, value := range []string{"class", "=Guaranteed", "image=nginx"} {
+		if _, err := parseContainerSelector(value); err == nil {
+			t.Errorf("parseContainerSelector(%q) = nil error", value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// container types shown in the TYPE column
+const (
+	appContainer       = "app"
+	initContainer      = "init"
+	sidecarContainer   = "sidecar"
+	ephemeralContainer = "ephemeral"
+)
+
+// containerTypeLegend follows a table printed with --show-type
+const containerTypeLegend = "# TYPE: app=runs for the life of the pod, init=runs to completion before the app containers, sidecar=init container with restartPolicy Always that keeps running next to them, ephemeral=added later with kubectl debug"
+
+// containerType says what kind of container of the pod the name is
+func containerType(p podqos.PodData, name string) string {
+	if p.Pod != nil {
+		for _, c := range p.Pod.Spec.EphemeralContainers {
+			if c.Name == name {
+				return ephemeralContainer
+			}
+		}
+	}
+	for _, c := range p.InitContainers {
+		if c.Name == name && c.Sidecar {
+			return sidecarContainer
+		}
+		if c.Name == name {
+			return initContainer
+		}
+	}
+	return appContainer
+}
+
+// withAllContainers returns copies of the pods with their init and
+// ephemeral containers listed along with the app containers, in the order
+// they start. Ephemeral containers can't set resources so they only have a
+// name
+func withAllContainers(podData []podqos.PodData) []podqos.PodData {
+	all := make([]podqos.PodData, len(podData))
+	for i, p := range podData {
+		containers := append([]podqos.ContainerData{}, p.InitContainers...)
+		containers = append(containers, p.Containers...)
+		if p.Pod != nil {
+			for _, c := range p.Pod.Spec.EphemeralContainers {
+				containers = append(containers, podqos.ContainerData{Name: c.Name})
+			}
+		}
+		p.Containers = containers
+		all[i] = p
+	}
+	return all
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestContainerType(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	sidecar := burstableContainer("proxy")
+	sidecar.RestartPolicy = &always
+	pod := newPod("default", "web", guaranteedContainer("app"))
+	pod.Spec.InitContainers = []v1.Container{bestEffortContainer("setup"), sidecar}
+	pod.Spec.EphemeralContainers = []v1.EphemeralContainer{{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger"}}}
+	p := toPodData(pod)[0]
+
+	want := map[string]string{"app": appContainer, "setup": initContainer, "proxy": sidecarContainer, "debugger": ephemeralContainer}
+	for name, typ := range want {
+		if got := containerType(p, name); got != typ {
+			t.Errorf("containerType(%s) = %s, want %s", name, got, typ)
+		}
+	}
+
+	all := withAllContainers([]podqos.PodData{p})[0].Containers
+	var order []string
+	for _, c := range all {
+		order = append(order, c.Name)
+	}
+	if got := strings.Join(order, ","); got != "setup,proxy,app,debugger" {
+		t.Errorf("withAllContainers order = %s, want setup,proxy,app,debugger", got)
+	}
+}
+
+func TestTypeColumn(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	sidecar := burstableContainer("proxy")
+	sidecar.RestartPolicy = &always
+	pod := newPod("default", "web", guaranteedContainer("app"))
+	pod.Spec.InitContainers = []v1.Container{bestEffortContainer("setup"), sidecar}
+	pod.Spec.EphemeralContainers = []v1.EphemeralContainer{{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger"}}}
+
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(pod), tableOptions{resources: cpuMemory, showType: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], "TYPE") {
+		t.Fatalf("table = %q, want a TYPE column and a row per container", lines)
+	}
+	for i, want := range []string{"setup " + initContainer, "proxy " + sidecarContainer, "app " + appContainer, "debugger " + ephemeralContainer} {
+		if !strings.Contains(strings.Join(strings.Fields(lines[i+1]), " "), want) {
+			t.Errorf("row %d = %q, want %q", i+1, lines[i+1], want)
+		}
+	}
+
+	// without --show-type only the app containers are listed
+	buf.Reset()
+	printTable(&buf, toPodData(pod), tableOptions{resources: cpuMemory})
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || strings.Contains(lines[0], "TYPE") {
+		t.Errorf("table = %q, want just the app container", lines)
+	}
+}
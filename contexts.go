@@ -0,0 +1,97 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// collectAllContexts lists the pods of every context in the kubeconfig in
+// name order, a context that can't be reached is reported on stderr and
+// skipped so the other clusters still show up. Each pod is labelled with
+// contextLabel of its context. When interrupted the contexts listed so far
+// are returned
+func collectAllContexts(clientCfg *clientcmdapi.Config, namespaceFlag string, allNameSpaces bool, opts collectOptions, contextPattern *regexp.Regexp) []podqos.PodData {
+	var names []string
+	for name := range clientCfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var podData []podqos.PodData
+	defer opts.progress.finish()
+	for _, name := range names {
+		if opts.interrupted() {
+			break
+		}
+		opts.progress.step("scanning", "context", name, len(names))
+		config, err := clientcmd.NewNonInteractiveClientConfig(*clientCfg, name, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
+			logger.warnf("skipping context %q: %v", name, err)
+			continue
+		}
+		opts.cluster.throttle(config)
+		contextConfig := rest.CopyConfig(config)
+		if opts.cluster.jsonProtocol {
+			useJSON(contextConfig)
+		} else {
+			useProtobuf(contextConfig)
+		}
+		clientset, err := kubernetes.NewForConfig(contextConfig)
+		if err != nil {
+			logger.warnf("skipping context %q: %v", name, err)
+			continue
+		}
+		contextOpts := opts
+		if opts.withUsage {
+			if contextOpts.metrics, err = metricsclientset.NewForConfig(config); err != nil {
+				logger.warnf("skipping context %q: %v", name, err)
+				continue
+			}
+		}
+		namespace := resolveNamespace(clientCfg.Contexts[name].Namespace, namespaceFlag, allNameSpaces)
+		pods, err := collect(clientset, namespace, contextOpts)
+		if err != nil && !opts.interrupted() {
+			logger.warnf("skipping context %q: %v", name, err)
+			continue
+		}
+		for i := range pods {
+			pods[i].Context = contextLabel(name, contextPattern)
+		}
+		podData = append(podData, pods...)
+	}
+	return podData
+}
+
+// contextLabel shortens the context name to the first capture group of the
+// pattern, e.g. "cluster/(.+)" turns an EKS arn into the cluster name. The
+// whole name is kept when there is no pattern or it doesn't match
+func contextLabel(name string, pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return name
+	}
+	match := pattern.FindStringSubmatch(name)
+	if len(match) < 2 || match[1] == "" {
+		return name
+	}
+	return match[1]
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestCollectAllContextsSkipsUnreachable(t *testing.T) {
+	server := newAPIServer(t, newPod("default", "web", burstableContainer("app")), newPod("other", "db", guaranteedContainer("pg")))
+	clientCfg := &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"reachable": {Server: server.URL},
+			// nothing listens on port 1
+			"unreachable": {Server: "http://127.0.0.1:1"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"user": {}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"prod":    {Cluster: "reachable", AuthInfo: "user", Namespace: "default"},
+			"staging": {Cluster: "unreachable", AuthInfo: "user"},
+		},
+	}
+	log := captureLog(t)
+
+	podData := collectAllContexts(clientCfg, "", false, collectOptions{}, nil)
+	if len(podData) != 1 || podData[0].PodName != "web" || podData[0].Context != "prod" {
+		t.Fatalf("collected %v, want the pod of the reachable context", names(podData))
+	}
+	if !strings.Contains(log.String(), `skipping context "staging"`) {
+		t.Errorf("log = %q, want the unreachable context skipped", log.String())
+	}
+
+	// the rows are prefixed with the context
+	var buf strings.Builder
+	printTable(&buf, podData, tableOptions{showContext: true})
+	lines := strings.Split(buf.String(), "\n")
+	if got := strings.Fields(lines[0]); got[0] != "CONTEXT" {
+		t.Errorf("header = %v, want CONTEXT first", got)
+	}
+	if got := strings.Fields(lines[1]); got[0] != "prod" || got[1] != "default" || got[2] != "web" {
+		t.Errorf("row = %v, want the prod context first", got)
+	}
+}
+
+func TestCollectAllContextsAllNamespaces(t *testing.T) {
+	server := newAPIServer(t, newPod("default", "web", burstableContainer("app")), newPod("other", "db", guaranteedContainer("pg")))
+	clientCfg := &clientcmdapi.Config{
+		Clusters:  map[string]*clientcmdapi.Cluster{"a": {Server: server.URL}, "b": {Server: server.URL}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"user": {}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"blue":  {Cluster: "a", AuthInfo: "user"},
+			"green": {Cluster: "b", AuthInfo: "user"},
+		},
+	}
+	podData := collectAllContexts(clientCfg, "", true, collectOptions{}, nil)
+	var got []string
+	for _, p := range podData {
+		got = append(got, p.Context+":"+p.NameSpace+"/"+p.PodName)
+	}
+	if strings.Join(got, " ") != "blue:default/web blue:other/db green:default/web green:other/db" {
+		t.Errorf("collected %v, want every pod of both contexts in context order", got)
+	}
+}
+
+func TestContextLabel(t *testing.T) {
+	pattern := regexp.MustCompile("cluster/(.+)")
+	tests := []struct {
+		name    string
+		pattern *regexp.Regexp
+		want    string
+	}{
+		{"arn:aws:eks:us-east-1:123456789012:cluster/payments", pattern, "payments"},
+		// no match keeps the whole name
+		{"kind-dev", pattern, "kind-dev"},
+		{"arn:aws:eks:us-east-1:123456789012:cluster/payments", nil, "arn:aws:eks:us-east-1:123456789012:cluster/payments"},
+		// an empty capture keeps the whole name too
+		{"gke_project_zone_", regexp.MustCompile("gke_[^_]+_[^_]+_(.*)"), "gke_project_zone_"},
+	}
+	for _, tt := range tests {
+		if got := contextLabel(tt.name, tt.pattern); got != tt.want {
+			t.Errorf("contextLabel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectAllContextsShortensLabels(t *testing.T) {
+	server := newAPIServer(t, newPod("default", "web", burstableContainer("app")))
+	clientCfg := &clientcmdapi.Config{
+		Clusters:  map[string]*clientcmdapi.Cluster{"a": {Server: server.URL}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"user": {}},
+		Contexts:  map[string]*clientcmdapi.Context{"arn:aws:eks:us-east-1:1:cluster/blue": {Cluster: "a", AuthInfo: "user"}},
+	}
+	opts := collectOptions{}
+	podData := collectAllContexts(clientCfg, "", false, opts, regexp.MustCompile("cluster/(.+)"))
+	if len(podData) != 1 || podData[0].Context != "blue" {
+		t.Errorf("collected %+v, want the web pod labelled blue", podData)
+	}
+}
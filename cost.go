@@ -0,0 +1,98 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	"sigs.k8s.io/yaml"
+)
+
+// hoursPerMonth is the average month, 365 days * 24 hours / 12
+const hoursPerMonth = 730
+
+// Pricing is the file given to --pricing-file, e.g.
+//
+//	cpuCoreHour: 0.031
+//	memoryGiBHour: 0.004
+type Pricing struct {
+	CPUCoreHour   float64 `json:"cpuCoreHour"`
+	MemoryGiBHour float64 `json:"memoryGiBHour"`
+}
+
+// loadPricing reads the pricing file
+func loadPricing(path string) (*Pricing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pricing Pricing
+	if err := yaml.UnmarshalStrict(data, &pricing); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing file %s: %v", path, err)
+	}
+	if pricing.CPUCoreHour < 0 || pricing.MemoryGiBHour < 0 {
+		return nil, fmt.Errorf("pricing file %s: prices can't be negative", path)
+	}
+	return &pricing, nil
+}
+
+// monthly estimates what the requests cost per month, requests are what the
+// scheduler reserves so they are what is paid for whatever the usage
+func (p *Pricing) monthly(requests podqos.ResourceData) float64 {
+	cores := float64(requests.CPU().MilliValue()) / 1000
+	gib := float64(requests.Memory().Value()) / (1 << 30)
+	return (cores*p.CPUCoreHour + gib*p.MemoryGiBHour) * hoursPerMonth
+}
+
+// formatCost renders a monthly cost, e.g. $12.34
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// printCostTotals writes the monthly cost of the app containers per context
+// and namespace, in the order each namespace was first seen
+func printCostTotals(w io.Writer, podData []podqos.PodData, pricing *Pricing, showContext bool) {
+	var keys []string
+	totals := map[string]float64{}
+	names := map[string][]string{}
+	for _, p := range podData {
+		key := p.Context + "/" + p.NameSpace
+		if _, ok := totals[key]; !ok {
+			keys = append(keys, key)
+			names[key] = []string{p.NameSpace}
+			if showContext {
+				names[key] = []string{p.Context, p.NameSpace}
+			}
+		}
+		for _, c := range p.Containers {
+			totals[key] += pricing.monthly(c.Requests)
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAMESPACE", "COST/MO"}
+	if showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, key := range keys {
+		fmt.Fprintln(tw, strings.Join(append(names[key], formatCost(totals[key])), "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePricing writes the pricing file and loads it
+func writePricing(t *testing.T, content string) (*Pricing, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadPricing(path)
+}
+
+func TestMonthlyCost(t *testing.T) {
+	pricing, err := writePricing(t, "cpuCoreHour: 0.04\nmemoryGiBHour: 0.008\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	podData := toPodData(
+		newPod("shop", "db", guaranteedContainer("pg")),
+		newPod("shop", "web", burstableContainer("app"), bestEffortContainer("proxy")),
+		newPod("batch", "job", bestEffortContainer("job")))
+	// 1 core and 1Gi for 730 hours
+	if got := formatCost(pricing.monthly(podData[0].Containers[0].Requests)); got != "$35.04" {
+		t.Errorf("cost of 1 cpu 1Gi = %s, want $35.04", got)
+	}
+	// 250m and 128Mi
+	if got := formatCost(pricing.monthly(podData[1].Containers[0].Requests)); got != "$8.03" {
+		t.Errorf("cost of 250m 128Mi = %s, want $8.03", got)
+	}
+	if got := formatCost(pricing.monthly(podData[1].Containers[1].Requests)); got != "$0.00" {
+		t.Errorf("cost without requests = %s, want $0.00", got)
+	}
+
+	var buf bytes.Buffer
+	printCostTotals(&buf, podData, pricing, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{"NAMESPACE COST/MO", "shop $43.07", "batch $0.00"} {
+		if got := strings.Join(strings.Fields(lines[i]), " "); got != want {
+			t.Errorf("line %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestLoadPricingRejects(t *testing.T) {
+	for _, content := range []string{
+		"cpuCoreHour: -0.04\n",
+		"cpuCoreHour: 0.04\ngpuHour: 1\n",
+		"cpuCoreHour: cheap\n",
+	} {
+		if _, err := writePricing(t, content); err == nil {
+			t.Errorf("loadPricing(%q) = nil error", content)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ownerCacheFile is the name of the cache file in --cache-dir
+const ownerCacheFile = "owners.json"
+
+// ownerCacheEntry is the controller of a ReplicaSet as of when it was looked up
+type ownerCacheEntry struct {
+	Owner   *metav1.OwnerReference `json:"owner"`
+	Expires time.Time              `json:"expires"`
+}
+
+// ownerDiskCache keeps ReplicaSet owner lookups between runs. Entries are
+// keyed by the ReplicaSet's UID, so a ReplicaSet that is deleted and created
+// again under the same name misses the cache, and expire after the ttl. A
+// nil cache never hits
+type ownerDiskCache struct {
+	mu      sync.Mutex
+	path    string
+	ttl     time.Duration
+	entries map[types.UID]ownerCacheEntry
+	dirty   bool
+}
+
+// loadOwnerDiskCache reads the cache in dir, creating dir if needed. A
+// missing or unreadable cache file starts out empty
+func loadOwnerDiskCache(dir string, ttl time.Duration) (*ownerDiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &ownerDiskCache{
+		path:    filepath.Join(dir, ownerCacheFile),
+		ttl:     ttl,
+		entries: map[types.UID]ownerCacheEntry{},
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil || json.Unmarshal(data, &c.entries) != nil {
+		c.entries = map[types.UID]ownerCacheEntry{}
+		return c, nil
+	}
+	now := time.Now()
+	for uid, entry := range c.entries {
+		if now.After(entry.Expires) {
+			delete(c.entries, uid)
+			c.dirty = true
+		}
+	}
+	return c, nil
+}
+
+// get returns the cached controller of the ReplicaSet with the uid
+func (c *ownerDiskCache) get(uid types.UID) (*metav1.OwnerReference, bool) {
+	if c == nil || uid == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[uid]
+	if !ok || time.Now().After(entry.Expires) {
+		return nil, false
+	}
+	return entry.Owner, true
+}
+
+// put caches the controller of the ReplicaSet with the uid
+func (c *ownerDiskCache) put(uid types.UID, owner *metav1.OwnerReference) {
+	if c == nil || uid == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[uid] = ownerCacheEntry{Owner: owner, Expires: time.Now().Add(c.ttl)}
+	c.dirty = true
+}
+
+// save writes the cache back when it changed, through a temporary file so
+// an interrupted run can't leave a truncated cache behind
+func (c *ownerDiskCache) save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
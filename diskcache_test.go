@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// resolveWeb resolves the workload of a web pod owned by ReplicaSet web-abc
+// in a new run using the disk cache in dir, returning the number of Gets
+func resolveWeb(t *testing.T, dir string, ttl time.Duration) int64 {
+	t.Helper()
+	disk, err := loadOwnerDiskCache(dir, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientset := fake.NewSimpleClientset(newReplicaSet("default", "web-abc", "web"))
+	gets := countGets(clientset, "replicasets")
+	pod := newPod("default", "web-abc-1", bestEffortContainer("app"))
+	ownedBy(pod, "ReplicaSet", "web-abc")
+	p := toPodData(pod)[0]
+	if kind, name, err := newOwnerResolver(clientset, disk).workload(context.Background(), p.NameSpace, p.Owner); err != nil || kind != "Deployment" || name != "web" {
+		t.Errorf("workload = %s/%s %v, want Deployment/web", kind, name, err)
+	}
+	if err := disk.save(); err != nil {
+		t.Fatal(err)
+	}
+	return *gets
+}
+
+func TestOwnerDiskCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if gets := resolveWeb(t, dir, time.Hour); gets != 1 {
+		t.Errorf("first run got the ReplicaSet %d times, want once", gets)
+	}
+	if _, err := os.ReadFile(filepath.Join(dir, ownerCacheFile)); err != nil {
+		t.Fatalf("cache file wasn't written: %v", err)
+	}
+	if gets := resolveWeb(t, dir, time.Hour); gets != 0 {
+		t.Errorf("second run got the ReplicaSet %d times, want a cache hit", gets)
+	}
+}
+
+func TestOwnerDiskCacheExpires(t *testing.T) {
+	dir := t.TempDir()
+	resolveWeb(t, dir, -time.Second)
+	if gets := resolveWeb(t, dir, time.Hour); gets != 1 {
+		t.Errorf("run after the ttl got the ReplicaSet %d times, want once", gets)
+	}
+}
+
+func TestOwnerDiskCacheUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ownerCacheFile), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if gets := resolveWeb(t, dir, time.Hour); gets != 1 {
+		t.Errorf("got the ReplicaSet %d times, want a fresh cache", gets)
+	}
+	var nilCache *ownerDiskCache
+	if _, ok := nilCache.get("uid"); ok {
+		t.Error("nil cache hit")
+	}
+}
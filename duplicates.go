@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// duplicateName is how a container sharing its name with another container
+// of the pod is shown, the index is its position in the pod spec. Container
+// names can't have brackets so this never clashes with a real name
+func duplicateName(name string, index int) string {
+	return fmt.Sprintf("%s[%d]", name, index)
+}
+
+// splitDuplicateName undoes duplicateName, ok is false for other names
+func splitDuplicateName(name string) (base string, index int, ok bool) {
+	open := strings.LastIndex(name, "[")
+	if open <= 0 || !strings.HasSuffix(name, "]") {
+		return name, 0, false
+	}
+	index, err := strconv.Atoi(name[open+1 : len(name)-1])
+	if err != nil {
+		return name, 0, false
+	}
+	return name[:open], index, true
+}
+
+// disambiguateContainers renames containers whose name isn't unique in
+// their pod with duplicateName. The api server rejects such pods but a
+// manifest or a broken object can still have them, and every lookup by
+// name would otherwise find the first one
+func disambiguateContainers(podData []podqos.PodData) {
+	for i := range podData {
+		renameDuplicates(podData[i].Containers)
+		renameDuplicates(podData[i].InitContainers)
+	}
+}
+
+// renameDuplicates renames the containers sharing a name
+func renameDuplicates(containers []podqos.ContainerData) {
+	count := map[string]int{}
+	for _, c := range containers {
+		count[c.Name]++
+	}
+	for i := range containers {
+		if count[containers[i].Name] > 1 {
+			containers[i].Name = duplicateName(containers[i].Name, i)
+		}
+	}
+}
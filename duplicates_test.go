@@ -0,0 +1,62 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestDuplicateContainerNames(t *testing.T) {
+	first := burstableContainer("app")
+	first.ReadinessProbe = &v1.Probe{}
+	pod := newPod("default", "web", first, bestEffortContainer("proxy"), guaranteedContainer("app"))
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{Name: "app", RestartCount: 1},
+		{Name: "proxy", RestartCount: 2},
+		{Name: "app", RestartCount: 3},
+	}
+	podData := toPodData(pod)
+	disambiguateContainers(podData)
+
+	var got []string
+	for _, c := range podData[0].Containers {
+		got = append(got, c.Name+"="+string(c.QosClass(cpuMemory))+"/"+restartsCell(podData[0], c.Name)+"/"+probeCells(podData[0], c.Name)[1])
+	}
+	want := "app[0]=Burstable/1/true proxy=BestEffort/2/false app[2]=Guaranteed/3/false"
+	if strings.Join(got, " ") != want {
+		t.Errorf("containers = %v, want %s", got, want)
+	}
+}
+
+func TestSplitDuplicateName(t *testing.T) {
+	for name, want := range map[string]struct {
+		base  string
+		index int
+		ok    bool
+	}{
+		"app[2]":  {"app", 2, true},
+		"app":     {"app", 0, false},
+		"[2]":     {"[2]", 0, false},
+		"app[x]":  {"app[x]", 0, false},
+		"a[1][3]": {"a[1]", 3, true},
+	} {
+		base, index, ok := splitDuplicateName(name)
+		if base != want.base || index != want.index || ok != want.ok {
+			t.Errorf("splitDuplicateName(%q) = %q %d %v, want %q %d %v", name, base, index, ok, want.base, want.index, want.ok)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// limitCell is the effective limit of the resource, unbounded when a
+// container of the pod has no limit for it
+func limitCell(style format.Style, limits podqos.ResourceData, name v1.ResourceName) string {
+	if !limits.Has(name) {
+		return "unbounded"
+	}
+	return format.Resource(style, name, limits.Get(name))
+}
+
+// printEffective writes one row per pod with its scheduler-effective
+// requests and limits, POD-LEVEL says they come from the pod-level
+// resources rather than the containers
+func printEffective(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAMESPACE", "POD NAME", "CPUl", "CPUr", "MEMl", "MEMr", "POD-LEVEL"}
+	if opts.showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, v := range podData {
+		requests, limits := v.EffectiveResources()
+		row := []string{
+			v.NameSpace,
+			v.PodName,
+			limitCell(opts.quantityStyle, limits, v1.ResourceCPU),
+			format.Resource(opts.quantityStyle, v1.ResourceCPU, requests.CPU()),
+			limitCell(opts.quantityStyle, limits, v1.ResourceMemory),
+			format.Resource(opts.quantityStyle, v1.ResourceMemory, requests.Memory()),
+			strconv.FormatBool(v.HasPodLevelResources()),
+		}
+		if opts.showContext {
+			row = append([]string{v.Context}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestPrintEffective(t *testing.T) {
+	// the bare sidecar leaves the pod without a cpu or memory limit
+	mixed := newPod("default", "mixed", newContainer("app", quantities("cpu", "1"), quantities("cpu", "1")), newContainer("sidecar", nil, quantities("cpu", "1")))
+	podLevel := newPod("default", "pod-level", bestEffortContainer("app"))
+	podLevel.Spec.Resources = &v1.ResourceRequirements{Limits: quantities("cpu", "2", "memory", "1Gi"), Requests: quantities("cpu", "1", "memory", "1Gi")}
+
+	var buf bytes.Buffer
+	printEffective(&buf, toPodData(mixed, podLevel), tableOptions{})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "NAMESPACE POD NAME CPUl CPUr MEMl MEMr POD-LEVEL" {
+		t.Errorf("header = %v", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "default mixed unbounded 2 unbounded 0 false" {
+		t.Errorf("mixed row = %q, want unbounded limits", got)
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "default pod-level 2 1 1Gi 1Gi true" {
+		t.Errorf("pod-level row = %q, want the pod-level resources", got)
+	}
+}
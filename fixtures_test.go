@@ -0,0 +1,152 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// quantities builds a resource list from name, quantity pairs, e.g.
+// quantities("cpu", "100m", "memory", "128Mi")
+func quantities(pairs ...string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
+
+// newContainer is a container spec with the limits and requests
+func newContainer(name string, limits, requests v1.ResourceList) v1.Container {
+	return v1.Container{Name: name, Image: name, Resources: v1.ResourceRequirements{Limits: limits, Requests: requests}}
+}
+
+// newPod is a scheduled pod with the containers
+func newPod(namespace, name string, containers ...v1.Container) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("uid-" + namespace + "-" + name)},
+		Spec:       v1.PodSpec{NodeName: "node-1", Containers: containers},
+	}
+}
+
+// ownedBy makes the owner the controller of the object
+func ownedBy(object metav1.Object, kind, name string) {
+	controller := true
+	object.SetOwnerReferences([]metav1.OwnerReference{{Kind: kind, Name: name, UID: types.UID("uid-" + object.GetNamespace() + "-" + name), Controller: &controller}})
+}
+
+// newReplicaSet is a ReplicaSet of the Deployment
+func newReplicaSet(namespace, name, deployment string) *appsv1.ReplicaSet {
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("uid-" + namespace + "-" + name)}}
+	ownedBy(rs, "Deployment", deployment)
+	return rs
+}
+
+// guaranteedContainer is a cpu and memory Guaranteed container
+func guaranteedContainer(name string) v1.Container {
+	list := quantities("cpu", "1", "memory", "1Gi")
+	return newContainer(name, list, list)
+}
+
+// burstableContainer is a container requesting less than its limits
+func burstableContainer(name string) v1.Container {
+	return newContainer(name, quantities("cpu", "1", "memory", "1Gi"), quantities("cpu", "250m", "memory", "128Mi"))
+}
+
+// bestEffortContainer is a container without any resources
+func bestEffortContainer(name string) v1.Container {
+	return newContainer(name, nil, nil)
+}
+
+// toPodData converts the pods the way collection does
+func toPodData(pods ...*v1.Pod) []podqos.PodData {
+	var data []podqos.PodData
+	for _, p := range pods {
+		data = append(data, podqos.NewPodData(*p))
+	}
+	return data
+}
+
+// cpuMemory is the resources the class is computed from in most tests
+var cpuMemory = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+
+// names is the namespace/name of every pod, for failure messages
+func names(podData []podqos.PodData) []string {
+	var names []string
+	for _, p := range podData {
+		names = append(names, p.NameSpace+"/"+p.PodName)
+	}
+	return names
+}
+
+// captureLog sends the warnings to a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	w := logger.w
+	logger.w = &buf
+	t.Cleanup(func() { logger.w = w })
+	return &buf
+}
+
+// newAPIServer is a json api server listing and getting the pods until the
+// test ends
+func newAPIServer(t *testing.T, pods ...*v1.Pod) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// /api/v1/pods, /api/v1/namespaces/<ns>/pods or .../pods/<name>, every
+		// /api/v1/namespaces/<ns> exists
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+		if len(parts) == 2 && parts[0] == "namespaces" {
+			json.NewEncoder(w).Encode(&v1.Namespace{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+				ObjectMeta: metav1.ObjectMeta{Name: parts[1]},
+			})
+			return
+		}
+		list := &v1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+		for _, p := range pods {
+			switch {
+			case len(parts) == 1 && parts[0] == "pods":
+			case len(parts) == 3 && parts[0] == "namespaces" && parts[1] == p.Namespace && parts[2] == "pods":
+			case len(parts) == 4 && parts[0] == "namespaces" && parts[1] == p.Namespace && parts[3] == p.Name:
+				pod := p.DeepCopy()
+				pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+				json.NewEncoder(w).Encode(pod)
+				return
+			default:
+				continue
+			}
+			list.Items = append(list.Items, *p)
+		}
+		if len(parts) == 4 {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// goImageHints are image name parts that suggest a go binary, images built
+// with ko or on a distroless static base mostly are
+var goImageHints = []string{"golang", "distroless/static", "ko.local"}
+
+// looksLikeGo guesses from the image, or a language label on the pod,
+// whether the container runs a go program
+func looksLikeGo(p podqos.PodData, image string) bool {
+	for _, key := range []string{"app.kubernetes.io/language", "language"} {
+		if strings.EqualFold(p.Labels[key], "go") || strings.EqualFold(p.Labels[key], "golang") {
+			return true
+		}
+	}
+	for _, hint := range goImageHints {
+		if strings.Contains(image, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// goCPUCheck warns about go containers limited to less than a core without
+// GOMAXPROCS set. Before Go 1.25 the runtime sizes GOMAXPROCS to the node's
+// cores, not the limit, so such a container burns through its quota and gets
+// throttled. This is a guess, a binary built with Go 1.25 or later or using
+// automaxprocs is fine and can't be told apart
+func goCPUCheck(podData []podqos.PodData) {
+	for _, p := range podData {
+		for _, c := range p.Containers {
+			spec := containerSpec(p, c.Name)
+			if spec == nil || !c.Limits.Has(v1.ResourceCPU) || c.Limits.CPU().MilliValue() >= 1000 {
+				continue
+			}
+			if !looksLikeGo(p, spec.Image) {
+				continue
+			}
+			tuned := false
+			for _, env := range spec.Env {
+				if env.Name == "GOMAXPROCS" {
+					tuned = true
+				}
+			}
+			if !tuned {
+				logger.warnf("%s/%s container %s looks like a go program with a cpu limit of %s and no GOMAXPROCS, it may be throttled if built with Go < 1.25", p.NameSpace, p.PodName, c.Name, c.Limits.CPU())
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGoCPUCheck(t *testing.T) {
+	goContainer := func(name, image, cpuLimit string) v1.Container {
+		c := newContainer(name, quantities("cpu", cpuLimit), nil)
+		c.Image = image
+		return c
+	}
+	tuned := goContainer("tuned", "golang:1.22", "500m")
+	tuned.Env = []v1.EnvVar{{Name: "GOMAXPROCS", Value: "1"}}
+	labelled := newPod("default", "api", goContainer("app", "registry.example.com/api:v2", "250m"))
+	labelled.Labels = map[string]string{"app.kubernetes.io/language": "Go"}
+	podData := toPodData(
+		newPod("default", "web", goContainer("flagged", "golang:1.22", "500m"), tuned, goContainer("whole", "golang:1.22", "2")),
+		newPod("default", "py", goContainer("app", "python:3.12", "500m")),
+		labelled)
+
+	buf := captureLog(t)
+	goCPUCheck(podData)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("warnings = %q, want the untuned go containers limited below a core", lines)
+	}
+	if !strings.Contains(lines[0], "default/web container flagged") || !strings.Contains(lines[0], "cpu limit of 500m and no GOMAXPROCS, it may be throttled if built with Go < 1.25") {
+		t.Errorf("warning = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "default/api container app") {
+		t.Errorf("warning = %q, want the pod labelled as go", lines[1])
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// NamespaceHeadroom is how far the Burstable pods of a namespace could burst
+// above their requests, per resource
+type NamespaceHeadroom struct {
+	Context   string
+	NameSpace string
+	Pods      int
+	Headroom  podqos.ResourceData
+	// Unbounded has the resources some container requests without a limit,
+	// so there is no upper bound on the burst
+	Unbounded map[v1.ResourceName]bool
+}
+
+// containerHeadroom is limit minus request, ok is false without a limit. A
+// limit without a request has no headroom since the request defaults to it
+func containerHeadroom(c podqos.ContainerData, name v1.ResourceName) (headroom resource.Quantity, ok bool) {
+	if !c.Limits.Has(name) {
+		return headroom, false
+	}
+	if !c.Requests.Has(name) {
+		return resource.Quantity{Format: c.Limits.Get(name).Format}, true
+	}
+	headroom = c.Limits.Get(name).DeepCopy()
+	headroom.Sub(*c.Requests.Get(name))
+	return headroom, true
+}
+
+// burstHeadrooms totals the headroom of the Burstable pods per context and
+// namespace, in the order each namespace was first seen
+func burstHeadrooms(podData []podqos.PodData, resources []v1.ResourceName) []NamespaceHeadroom {
+	var headrooms []NamespaceHeadroom
+	index := map[string]int{}
+	for i := range podData {
+		pod := podData[i]
+		if pod.QosClass(resources) != podqos.Burstable {
+			continue
+		}
+		key := pod.Context + "/" + pod.NameSpace
+		n, ok := index[key]
+		if !ok {
+			n = len(headrooms)
+			index[key] = n
+			headrooms = append(headrooms, NamespaceHeadroom{
+				Context:   pod.Context,
+				NameSpace: pod.NameSpace,
+				Headroom:  podqos.ResourceData{},
+				Unbounded: map[v1.ResourceName]bool{},
+			})
+		}
+		headrooms[n].Pods++
+		for _, c := range pod.Containers {
+			for _, name := range resources {
+				h, ok := containerHeadroom(c, name)
+				if !ok {
+					headrooms[n].Unbounded[name] = true
+					continue
+				}
+				sum := headrooms[n].Headroom.Get(name)
+				sum.Add(h)
+				headrooms[n].Headroom[name] = *sum
+			}
+		}
+	}
+	return headrooms
+}
+
+// printHeadrooms writes one row per namespace with its Burstable pods and
+// their total headroom per resource
+func printHeadrooms(w io.Writer, headrooms []NamespaceHeadroom, resources []v1.ResourceName, style format.Style, showContext bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAMESPACE", "BURSTABLE PODS"}
+	for _, name := range resources {
+		header = append(header, resourceHeader(name)+"-HEADROOM")
+	}
+	if showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, h := range headrooms {
+		row := []string{h.NameSpace, strconv.Itoa(h.Pods)}
+		for _, name := range resources {
+			if h.Unbounded[name] {
+				row = append(row, "unbounded")
+				continue
+			}
+			row = append(row, format.Resource(style, name, h.Headroom.Get(name)))
+		}
+		if showContext {
+			row = append([]string{h.Context}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
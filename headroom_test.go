@@ -0,0 +1,57 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestBurstHeadrooms(t *testing.T) {
+	podData := toPodData(
+		newPod("shop", "web", burstableContainer("app"), burstableContainer("proxy")),
+		newPod("shop", "api", burstableContainer("app")),
+		newPod("shop", "db", guaranteedContainer("pg")),
+		// a limit without a request has no headroom, a request without a limit is unbounded
+		newPod("batch", "job", newContainer("job", quantities("memory", "1Gi"), quantities("cpu", "500m"))))
+	headrooms := burstHeadrooms(podData, cpuMemory)
+	if len(headrooms) != 2 {
+		t.Fatalf("headrooms = %v, want shop and batch", headrooms)
+	}
+	shop := headrooms[0]
+	if shop.NameSpace != "shop" || shop.Pods != 2 {
+		t.Errorf("first = %s with %d pods, want shop with the 2 Burstable pods", shop.NameSpace, shop.Pods)
+	}
+	for name, want := range map[v1.ResourceName]string{v1.ResourceCPU: "2250m", v1.ResourceMemory: "2688Mi"} {
+		if got := shop.Headroom.Get(name); got.Cmp(resource.MustParse(want)) != 0 {
+			t.Errorf("shop %s headroom = %s, want %s", name, got, want)
+		}
+	}
+	batch := headrooms[1]
+	if !batch.Unbounded[v1.ResourceCPU] || batch.Unbounded[v1.ResourceMemory] || !batch.Headroom.Get(v1.ResourceMemory).IsZero() {
+		t.Errorf("batch = %+v, want unbounded cpu and no memory headroom", batch)
+	}
+
+	var buf bytes.Buffer
+	printHeadrooms(&buf, headrooms, cpuMemory, format.Raw, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "batch 1 unbounded 0" {
+		t.Errorf("batch row = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadKey identifies a scalable workload within a namespace
+func workloadKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// annotateHPA sets the HPA of every pod whose workload is the scaleTargetRef
+// of a HorizontalPodAutoscaler. BestEffort pods can't be scaled on cpu
+// utilization, so this is worth seeing next to the class
+func annotateHPA(ctx context.Context, clientset kubernetes.Interface, namespace string, podData []podqos.PodData, ownerCache *ownerDiskCache) error {
+	hpas, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(hpas.Items) == 0 {
+		return nil
+	}
+	targets := map[string]string{}
+	for _, hpa := range hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		targets[workloadKey(hpa.Namespace, ref.Kind, ref.Name)] = hpa.Name
+	}
+	owners := newOwnerResolver(clientset, ownerCache)
+	for i := range podData {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		kind, name, err := owners.workload(ctx, podData[i].NameSpace, podData[i].Owner)
+		if err != nil {
+			return err
+		}
+		podData[i].HPA = targets[workloadKey(podData[i].NameSpace, kind, name)]
+	}
+	return nil
+}
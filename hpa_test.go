@@ -0,0 +1,56 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAnnotateHPA(t *testing.T) {
+	web := newPod("default", "web-abc-1", bestEffortContainer("app"))
+	ownedBy(web, "ReplicaSet", "web-abc")
+	worker := newPod("default", "worker-0", bestEffortContainer("app"))
+	ownedBy(worker, "StatefulSet", "worker")
+	bare := newPod("default", "debug", bestEffortContainer("shell"))
+	hpa := func(name, kind, target string) *autoscalingv1.HorizontalPodAutoscaler {
+		return &autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       autoscalingv1.HorizontalPodAutoscalerSpec{ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: kind, Name: target}},
+		}
+	}
+	clientset := fake.NewSimpleClientset(newReplicaSet("default", "web-abc", "web"), hpa("web-hpa", "Deployment", "web"), hpa("other-hpa", "Deployment", "other"))
+
+	podData := toPodData(web, worker, bare)
+	if err := annotateHPA(context.Background(), clientset, "default", podData, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"web-hpa", "", ""} {
+		if podData[i].HPA != want {
+			t.Errorf("%s HPA = %q, want %q", podData[i].PodName, podData[i].HPA, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{showHPA: true})
+	if lines := strings.Split(buf.String(), "\n"); !strings.HasSuffix(strings.TrimSpace(lines[1]), "web-hpa") || !strings.HasSuffix(strings.TrimSpace(lines[2]), "<none>") {
+		t.Errorf("table = %q, want the HPA column", buf.String())
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package format renders resource quantities for display, every output mode
+// goes through here so cpu and memory look the same everywhere
+package format
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// binary suffixes from largest to smallest, memory is shown in the largest
+// one that fits
+var memoryUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"Ei", 1 << 60},
+	{"Pi", 1 << 50},
+	{"Ti", 1 << 40},
+	{"Gi", 1 << 30},
+	{"Mi", 1 << 20},
+	{"Ki", 1 << 10},
+}
+
+// CPU renders a cpu quantity as whole cores when it has no fractional part,
+// otherwise as millicores, e.g. "2" or "250m". Anything below a millicore is
+// rounded up to "1m"
+func CPU(q *resource.Quantity) string {
+	if q == nil {
+		return "0"
+	}
+	m := q.MilliValue()
+	if m%1000 == 0 {
+		return strconv.FormatInt(m/1000, 10)
+	}
+	return strconv.FormatInt(m, 10) + "m"
+}
+
+// Memory renders a memory quantity in the largest binary unit that is at
+// least one, rounded to one decimal, e.g. "128Mi" or "1.5Gi". A value that
+// rounds up to 1024 of a unit moves up to the next, 1023.99Mi is "1Gi"
+// rather than "1024Mi". Values under 1Ki are shown in bytes
+func Memory(q *resource.Quantity) string {
+	if q == nil {
+		return "0"
+	}
+	v := q.Value()
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	for i, unit := range memoryUnits {
+		if float64(v) < unit.size {
+			continue
+		}
+		rounded := round(float64(v) / unit.size)
+		if rounded >= 1024 && i > 0 {
+			unit = memoryUnits[i-1]
+			rounded = round(float64(v) / unit.size)
+		}
+		return sign + strconv.FormatFloat(rounded, 'f', -1, 64) + unit.suffix
+	}
+	return sign + strconv.FormatInt(v, 10)
+}
+
+// round keeps a single decimal
+func round(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+// Style picks how quantities are rendered
+type Style string
+
+const (
+	// Human rounds to sensible units, cores for cpu and binary units for memory
+	Human Style = "human"
+
+	// Raw prints the quantity the way the api returns it, in the suffix
+	// family it was set with, binary (Mi, Gi), decimal (m, k, M) or an
+	// exponent. The api server keeps the family but not the exact spelling,
+	// 1000m comes back as 1 and 1024Mi as 1Gi
+	Raw Style = "raw"
+
+	// Scientific prints the value in base units with an exponent, e.g. 1.5e+09
+	Scientific Style = "scientific"
+)
+
+// Styles lists every supported style
+var Styles = []Style{Human, Raw, Scientific}
+
+// ParseStyle validates a style name, empty means Human
+func ParseStyle(value string) (Style, error) {
+	if value == "" {
+		return Human, nil
+	}
+	for _, style := range Styles {
+		if string(style) == value {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("unknown quantity format %q, use one of raw, human or scientific", value)
+}
+
+// Resource renders a quantity of the named resource in the given style. In
+// the Human style cpu is shown as cores, memory and storage in binary units
+// and anything else, like gpus, as is
+func Resource(style Style, name v1.ResourceName, q *resource.Quantity) string {
+	if q == nil {
+		q = &resource.Quantity{}
+	}
+	switch style {
+	case Raw:
+		return q.String()
+	case Scientific:
+		return scientific(q)
+	}
+	switch name {
+	case v1.ResourceCPU:
+		return CPU(q)
+	case v1.ResourceMemory, v1.ResourceEphemeralStorage, v1.ResourceStorage:
+		return Memory(q)
+	}
+	return q.String()
+}
+
+// scientific renders the value in base units, cores or bytes, with an
+// exponent
+func scientific(q *resource.Quantity) string {
+	dec := q.DeepCopy()
+	f, err := strconv.ParseFloat(dec.AsDec().String(), 64)
+	if err != nil {
+		return q.String()
+	}
+	return strconv.FormatFloat(f, 'e', -1, 64)
+}
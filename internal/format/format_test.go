@@ -0,0 +1,146 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package format
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCPU(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0", "0"},
+		{"250m", "250m"},
+		{"1", "1"},
+		{"1500m", "1500m"},
+		{"2000m", "2"},
+		{"100n", "1m"},
+		{"-250m", "-250m"},
+		{"64k", "64000"},
+	}
+	for _, tt := range tests {
+		q := resource.MustParse(tt.in)
+		if got := CPU(&q); got != tt.want {
+			t.Errorf("CPU(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := CPU(nil); got != "0" {
+		t.Errorf("CPU(nil) = %q, want %q", got, "0")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0", "0"},
+		{"512", "512"},
+		{"1023", "1023"},
+		{"1Ki", "1Ki"},
+		{"128Mi", "128Mi"},
+		{"1Gi", "1Gi"},
+		{"1536Mi", "1.5Gi"},
+		{"1000Mi", "1000Mi"},
+		{"2Ti", "2Ti"},
+		{"1T", "931.3Gi"},
+		{"-128Mi", "-128Mi"},
+		// rounding up to 1024 of a unit moves to the next one
+		{"1048575", "1Mi"},
+		{"1073731461", "1Gi"},
+	}
+	for _, tt := range tests {
+		q := resource.MustParse(tt.in)
+		if got := Memory(&q); got != tt.want {
+			t.Errorf("Memory(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := Memory(nil); got != "0" {
+		t.Errorf("Memory(nil) = %q, want %q", got, "0")
+	}
+}
+
+func TestResource(t *testing.T) {
+	tests := []struct {
+		name       v1.ResourceName
+		in         string
+		raw        string
+		human      string
+		scientific string
+	}{
+		{v1.ResourceCPU, "0", "0", "0", "0e+00"},
+		{v1.ResourceCPU, "250m", "250m", "250m", "2.5e-01"},
+		{v1.ResourceCPU, "1", "1", "1", "1e+00"},
+		{v1.ResourceMemory, "0", "0", "0", "0e+00"},
+		{v1.ResourceMemory, "128Mi", "128Mi", "128Mi", "1.34217728e+08"},
+		{v1.ResourceMemory, "1Gi", "1Gi", "1Gi", "1.073741824e+09"},
+		{v1.ResourceMemory, "1Ti", "1Ti", "1Ti", "1.099511627776e+12"},
+		{v1.ResourceMemory, "1T", "1T", "931.3Gi", "1e+12"},
+		{v1.ResourceEphemeralStorage, "2Gi", "2Gi", "2Gi", "2.147483648e+09"},
+		{"nvidia.com/gpu", "2", "2", "2", "2e+00"},
+	}
+	for _, tt := range tests {
+		q := resource.MustParse(tt.in)
+		for style, want := range map[Style]string{Raw: tt.raw, Human: tt.human, Scientific: tt.scientific} {
+			if got := Resource(style, tt.name, &q); got != want {
+				t.Errorf("Resource(%s, %s, %s) = %q, want %q", style, tt.name, tt.in, got, want)
+			}
+		}
+	}
+	if got := Resource(Human, v1.ResourceCPU, nil); got != "0" {
+		t.Errorf("Resource of nil = %q, want 0", got)
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	for in, want := range map[string]Style{"": Human, "human": Human, "raw": Raw, "scientific": Scientific} {
+		if got, err := ParseStyle(in); got != want || err != nil {
+			t.Errorf("ParseStyle(%q) = %q %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseStyle("si"); err == nil {
+		t.Error("ParseStyle(si) = nil error, want one")
+	}
+}
+
+func TestRawKeepsSuffixFamily(t *testing.T) {
+	// the api server canonicalizes within the family the quantity was set in
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1000m", "1"},
+		{"1500m", "1500m"},
+		{"0.5", "500m"},
+		{"2k", "2k"},
+		{"512M", "512M"},
+		{"1024Mi", "1Gi"},
+		{"1000Mi", "1000Mi"},
+		{"1.5Gi", "1536Mi"},
+		{"1e3", "1e3"},
+		{"12e6", "12e6"},
+	}
+	for _, tt := range tests {
+		q := resource.MustParse(tt.in)
+		if got := Resource(Raw, v1.ResourceMemory, &q); got != tt.want {
+			t.Errorf("Resource(raw, %s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
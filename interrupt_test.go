@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestInterruptKeepsWhatWasCollected(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newPod("fast", "web", bestEffortContainer("app")),
+		newPod("slow", "db", bestEffortContainer("pg")))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listed := make(chan struct{})
+	// listing the slow namespace blocks until Ctrl-C
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "slow" {
+			return false, nil, nil
+		}
+		close(listed)
+		<-ctx.Done()
+		return true, nil, ctx.Err()
+	})
+	go func() {
+		<-listed
+		// give the fast namespace time to finish
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	opts := collectOptions{ctx: ctx}
+	podData, err := collectNamespaces(clientset, []string{"fast", "slow"}, opts)
+	if err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	if !opts.interrupted() {
+		t.Error("interrupted() = false after cancel")
+	}
+	if len(podData) != 1 || podData[0].PodName != "web" {
+		t.Errorf("collected %v, want the pod of the fast namespace", names(podData))
+	}
+	// what was collected is still printed
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{resources: cpuMemory})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "fast ") || strings.Contains(buf.String(), "slow") {
+		t.Errorf("table = %q, want the row of the fast namespace", lines)
+	}
+}
+
+func TestInterruptMidList(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the first page comes back, the next one blocks until Ctrl-C
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.ListActionImpl).ListOptions.Continue == "" {
+			first := &v1.PodList{Items: []v1.Pod{*newPod("default", "web-0", bestEffortContainer("app")), *newPod("default", "web-1", bestEffortContainer("app"))}}
+			first.Continue = "page-2"
+			return true, first, nil
+		}
+		go cancel()
+		<-ctx.Done()
+		return true, nil, ctx.Err()
+	})
+	opts := collectOptions{ctx: ctx}
+	podData, err := collect(clientset, "default", opts)
+	if err == nil || !opts.interrupted() {
+		t.Errorf("collect = %v, want it interrupted", err)
+	}
+	if got := strings.Join(names(podData), " "); got != "default/web-0 default/web-1" {
+		t.Errorf("collected %q, want the first page", got)
+	}
+}
+
+func TestWaitForClassInterrupted(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", bestEffortContainer("app")))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := waitForClass(ctx, clientset, "default", "web", "Guaranteed", cpuMemory)
+	if err == nil || err.Error() != "interrupted waiting for pod web to become Guaranteed" {
+		t.Errorf("waitForClass = %v, want interrupted", err)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// JUnitSuite is the single test suite --junit writes, CI dashboards show
+// every pod as a test and every violation as its failure
+type JUnitSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is a single pod, the class name is its namespace
+type JUnitTestCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []JUnitFailure `xml:"failure,omitempty"`
+}
+
+// JUnitFailure is a violated rule
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// newJUnitSuite turns the audit into a test suite, with a test case per pod
+// and a failure per rule it violates
+func newJUnitSuite(podData []podqos.PodData, violations []Violation) JUnitSuite {
+	failures := map[[3]string][]JUnitFailure{}
+	for _, v := range violations {
+		key := [3]string{v.Context, v.Namespace, v.Pod}
+		failures[key] = append(failures[key], JUnitFailure{
+			Message: fmt.Sprintf("%s is below %s", v.Class, v.Rule.MinClass),
+			Type:    "AuditPolicyViolation",
+			Text:    fmt.Sprintf("pod %s/%s is %s, rule %s requires at least %s", v.Namespace, v.Pod, v.Class, v.Rule, v.Rule.MinClass),
+		})
+	}
+	suite := JUnitSuite{Name: "podqos", Tests: len(podData), Cases: []JUnitTestCase{}}
+	for _, p := range podData {
+		className := p.NameSpace
+		if p.Context != "" {
+			className = p.Context + "." + p.NameSpace
+		}
+		testCase := JUnitTestCase{
+			ClassName: className,
+			Name:      p.PodName,
+			Failures:  failures[[3]string{p.Context, p.NameSpace, p.PodName}],
+		}
+		// failures counts the failed tests, not the violations
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	return suite
+}
+
+// writeJUnit writes the suite to path as xml
+func writeJUnit(path string, suite JUnitSuite) error {
+	out, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJUnitReport(t *testing.T) {
+	policy, err := loadPolicy(writePolicy(t, "rules:\n- namespace: prod\n  minClass: Guaranteed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	podData := toPodData(
+		newPod("prod", "db", guaranteedContainer("pg")),
+		newPod("prod", "api", burstableContainer("app")),
+		newPod("staging", "web", bestEffortContainer("app")))
+	path := filepath.Join(t.TempDir(), "podqos.xml")
+	if err := writeJUnit(path, newJUnitSuite(podData, auditPods(policy, podData))); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Errorf("report = %q, want an xml header", data)
+	}
+	var suite JUnitSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+	if suite.Tests != 3 || suite.Failures != 1 || len(suite.Cases) != 3 {
+		t.Fatalf("suite = %d tests %d failures, want 3 and 1", suite.Tests, suite.Failures)
+	}
+	for _, c := range suite.Cases {
+		failed := len(c.Failures) > 0
+		if failed != (c.Name == "api") {
+			t.Errorf("%s.%s failures = %+v", c.ClassName, c.Name, c.Failures)
+		}
+	}
+	if !strings.Contains(string(data), `<failure message="Burstable is below Guaranteed" type="AuditPolicyViolation">`) {
+		t.Errorf("report = %s, want a failure element for prod/api", data)
+	}
+}
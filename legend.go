@@ -0,0 +1,91 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// legend explains the classes in the order kubelet evicts them and the OOM
+// killer picks them under node pressure
+const legend = `QoS classes, evicted and OOM killed first to last:
+  BestEffort  no requests or limits set, first to go under pressure
+  Burstable   requests set below limits, evicted next, those using most above their requests first
+  Guaranteed  requests equal to limits, last to be evicted`
+
+// printLegend writes the class key
+func printLegend(w io.Writer) {
+	fmt.Fprintln(w, legend)
+}
+
+// shortClasses are the single letters --short-class prints
+var shortClasses = map[podqos.PodQosPolicy]string{
+	podqos.Guaranteed: "G",
+	podqos.Burstable:  "B",
+	podqos.BestEffort: "E",
+}
+
+// shortClassLegend follows a table printed with --short-class
+const shortClassLegend = "# G=Guaranteed B=Burstable E=BestEffort"
+
+// evictionExplanations say when kubelet evicts a pod of each class
+var evictionExplanations = map[podqos.PodQosPolicy]string{
+	podqos.BestEffort: "evicted first under memory or disk pressure, it has no requests so all of its usage counts against it",
+	podqos.Burstable:  "evicted after BestEffort pods once it uses more than it requests, pods furthest above their requests go first",
+	podqos.Guaranteed: "evicted last, only when system daemons need the memory and no BestEffort or Burstable pods are left, or when it exceeds its limits",
+}
+
+// bestEffortReason says why a BestEffort pod is one, naming the containers
+// that set none of the resources, empty for the other classes
+func bestEffortReason(p podqos.PodData, resources []v1.ResourceName) string {
+	if p.QosClass(resources) != podqos.BestEffort {
+		return ""
+	}
+	if len(p.Containers) == 0 {
+		return "the pod has no containers"
+	}
+	var bare []string
+	for _, c := range p.Containers {
+		if c.QosClass(resources) == podqos.BestEffort {
+			bare = append(bare, c.Name)
+		}
+	}
+	var names []string
+	for _, name := range resources {
+		names = append(names, string(name))
+	}
+	noun := "containers"
+	if len(bare) == 1 {
+		noun = "container"
+	}
+	return fmt.Sprintf("no %s requests or limits on %s %s", strings.Join(names, "/"), noun, strings.Join(bare, ", "))
+}
+
+// printEvictionExplanations writes each pod's class and under what
+// conditions kubelet would evict it, and for BestEffort pods why they are
+func printEvictionExplanations(w io.Writer, podData []podqos.PodData, resources []v1.ResourceName) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPOD NAME\tCLASS\tEVICTION\tREASON")
+	for _, p := range podData {
+		class := p.QosClass(resources)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.NameSpace, p.PodName, class, evictionExplanations[class], bestEffortReason(p, resources))
+	}
+	tw.Flush()
+}
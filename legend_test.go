@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+func TestPrintLegend(t *testing.T) {
+	var buf bytes.Buffer
+	printLegend(&buf)
+	legend := buf.String()
+	if !strings.HasPrefix(legend, "QoS classes, evicted and OOM killed first to last:\n") {
+		t.Errorf("legend = %q", legend)
+	}
+	// the classes are listed in eviction order
+	bestEffort, burstable, guaranteed := strings.Index(legend, "BestEffort"), strings.Index(legend, "Burstable"), strings.Index(legend, "Guaranteed")
+	if bestEffort < 0 || !(bestEffort < burstable && burstable < guaranteed) {
+		t.Errorf("legend = %q, want BestEffort, Burstable then Guaranteed", legend)
+	}
+}
+
+func TestPrintEvictionExplanations(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "db", guaranteedContainer("pg")),
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "batch", bestEffortContainer("job")))
+	var buf bytes.Buffer
+	printEvictionExplanations(&buf, podData, cpuMemory)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("explanations = %q, want a row per pod", lines)
+	}
+	for i, class := range []string{"Guaranteed", "Burstable", "BestEffort"} {
+		if !strings.Contains(lines[i+1], " "+class+" ") || !strings.Contains(lines[i+1], evictionExplanations[podqos.PodQosPolicy(class)]) {
+			t.Errorf("row %d = %q, want the %s explanation", i+1, lines[i+1], class)
+		}
+	}
+	if !strings.Contains(lines[1], "evicted last") || !strings.Contains(lines[3], "evicted first") {
+		t.Errorf("explanations = %q", lines)
+	}
+}
+
+func TestShortClass(t *testing.T) {
+	for class, want := range map[podqos.PodQosPolicy]string{
+		podqos.Guaranteed: "G",
+		podqos.Burstable:  "B",
+		podqos.BestEffort: "E",
+	} {
+		if got := classCell(class, tableOptions{shortClass: true}); got != want {
+			t.Errorf("short %s = %q, want %q", class, got, want)
+		}
+		if got := classCell(class, tableOptions{}); got != string(class) {
+			t.Errorf("%s = %q, want the full name", class, got)
+		}
+		// the legend names every letter
+		if !strings.Contains(shortClassLegend, want+"="+string(class)) {
+			t.Errorf("legend %q doesn't explain %s", shortClassLegend, want)
+		}
+	}
+	podData := toPodData(newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy")))
+	var buf bytes.Buffer
+	printFlat(&buf, podData, tableOptions{resources: cpuMemory, shortClass: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{"B", "E"} {
+		if fields := strings.Fields(lines[i+1]); fields[len(fields)-1] != want {
+			t.Errorf("row %d = %q, want class %s", i+1, lines[i+1], want)
+		}
+	}
+}
+
+func TestBestEffortReason(t *testing.T) {
+	tests := []struct {
+		pod  podqos.PodData
+		want string
+	}{
+		{toPodData(newPod("default", "batch", bestEffortContainer("job")))[0], "no cpu/memory requests or limits on container job"},
+		{toPodData(newPod("default", "web", bestEffortContainer("app"), bestEffortContainer("proxy")))[0], "no cpu/memory requests or limits on containers app, proxy"},
+		{toPodData(newPod("default", "empty"))[0], "the pod has no containers"},
+		{toPodData(newPod("default", "api", burstableContainer("app"), bestEffortContainer("proxy")))[0], ""},
+		{toPodData(newPod("default", "db", guaranteedContainer("pg")))[0], ""},
+	}
+	for _, tt := range tests {
+		if got := bestEffortReason(tt.pod, cpuMemory); got != tt.want {
+			t.Errorf("%s reason = %q, want %q", tt.pod.PodName, got, tt.want)
+		}
+	}
+}
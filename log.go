@@ -0,0 +1,78 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logFormats are the values accepted by --log-format
+var logFormats = []string{"text", "json"}
+
+// diagLogger writes warnings and other diagnostics, never the report, as
+// text or as one json object per line for log pipelines
+type diagLogger struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+// logger is where every diagnostic goes, set up from --log-format
+var logger = &diagLogger{w: os.Stderr}
+
+// setFormat switches the logger to the given format, one of logFormats
+func (l *diagLogger) setFormat(format string) error {
+	switch format {
+	case "text":
+		l.json = false
+	case "json":
+		l.json = true
+	default:
+		return fmt.Errorf("unsupported log format %q, allowed formats are: %s", format, strings.Join(logFormats, ", "))
+	}
+	return nil
+}
+
+// log writes a single message at the level
+func (l *diagLogger) log(level, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.json {
+		fmt.Fprintf(l.w, "%s: %s\n", level, msg)
+		return
+	}
+	json.NewEncoder(l.w).Encode(struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}{time.Now().UTC().Format(time.RFC3339), level, msg})
+}
+
+// warnf logs a warning, e.g. a context that was skipped
+func (l *diagLogger) warnf(format string, args ...interface{}) {
+	l.log("warning", format, args...)
+}
+
+// infof logs progress worth knowing about, e.g. the address being served on
+func (l *diagLogger) infof(format string, args ...interface{}) {
+	l.log("info", format, args...)
+}
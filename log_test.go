@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogs(t *testing.T) {
+	buf := captureLog(t)
+	defer logger.setFormat("text")
+	if err := logger.setFormat("json"); err != nil {
+		t.Fatal(err)
+	}
+	logger.warnf("skipping context %q: %v", "staging", "connection refused")
+	logger.infof("serving on %s", ":8080")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %q, want a line per message", lines)
+	}
+	for i, want := range []struct{ level, msg string }{
+		{"warning", `skipping context "staging": connection refused`},
+		{"info", "serving on :8080"},
+	} {
+		var entry struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("line %q isn't json: %v", lines[i], err)
+		}
+		if entry.Level != want.level || entry.Msg != want.msg {
+			t.Errorf("line %d = %s %q, want %s %q", i, entry.Level, entry.Msg, want.level, want.msg)
+		}
+		if _, err := time.Parse(time.RFC3339, entry.Time); err != nil {
+			t.Errorf("time = %q: %v", entry.Time, err)
+		}
+	}
+}
+
+func TestTextLogs(t *testing.T) {
+	buf := captureLog(t)
+	if err := logger.setFormat("text"); err != nil {
+		t.Fatal(err)
+	}
+	logger.warnf("no pods found")
+	if got := buf.String(); got != "warning: no pods found\n" {
+		t.Errorf("text log = %q", got)
+	}
+	if err := logger.setFormat("xml"); err == nil {
+		t.Error("setFormat(xml) = nil error")
+	}
+}
@@ -14,144 +14,1411 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"text/template"
+	"time"
 
-	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	// register the gcp, azure and oidc auth providers
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/jsonpath"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
-// ResourceData containts CPU/Memory quantity
-type ResourceData struct {
-	CPU *resource.Quantity
-	Memory *resource.Quantity
+// parseResources parses the --resources flag, e.g. cpu,memory,nvidia.com/gpu
+func parseResources(value string) []v1.ResourceName {
+	var resources []v1.ResourceName
+	for _, name := range splitList(value) {
+		resources = append(resources, v1.ResourceName(name))
+	}
+	if len(resources) == 0 {
+		return podqos.DefaultResources
+	}
+	return resources
 }
-// ContainerData holds container information
-type ContainerData struct {
-	Name string
-	Limits ResourceData
-	Requests ResourceData
+
+// collectOptions turns on the optional lookups done after listing the pods
+type collectOptions struct {
+	// nodes caches the node lookups across the namespaces of
+	// collectNamespaces, nil caches them for a single collect
+	nodes *nodeLookup
+	// withHPA matches HorizontalPodAutoscalers to the pods they scale
+	withHPA bool
+	// maxPods stops listing after that many pods, zero is unlimited
+	maxPods int
+	// podName fetches just that pod instead of listing the namespace
+	podName string
+	// withNodeStatus looks up whether each pod's node is cordoned or tainted
+	withNodeStatus bool
+	// withNodeAllocatable looks up the allocatable resources of each pod's node
+	withNodeAllocatable bool
+	// withOS works out the operating system of each pod
+	withOS bool
+	// progress reports the namespaces and contexts scanned and the pages of
+	// a list of every namespace, nil is silent
+	progress *progress
+	// checkNamespace fails on a missing namespace instead of listing no pods
+	checkNamespace bool
+	// ownerCache keeps owner lookups between runs, nil only caches for this run
+	ownerCache *ownerDiskCache
+	// withNodePressure looks up the pressure conditions of each pod's node
+	withNodePressure bool
+	// withUsage looks up the usage of each container from the metrics api
+	withUsage bool
+	// metrics is the client withUsage uses, set per context with --all-contexts
+	metrics metricsclientset.Interface
+	// fieldSelector limits the pods listed, e.g. to leave out namespaces
+	fieldSelector string
+	// ctx stops collection when done, on Ctrl-C. nil never stops
+	ctx context.Context
+	// cluster holds the client flags, for the clients of --all-contexts
+	cluster clusterFlags
 }
-// PodData holds pod information, and list of containers in pod
-type PodData struct {
-	PodName string
-	NameSpace string
-	Containers []ContainerData
+
+// context is the context collection runs under
+func (o collectOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
 }
 
-// PodQosPolicy describes the QosClass for each container
-// see: https://kubernetes.io/docs/tasks/administer-cluster/cpu-management-policies/
-// for more information
-type PodQosPolicy  string
+// interrupted reports whether collection was stopped early, what was
+// collected up to then is kept
+func (o collectOptions) interrupted() bool {
+	return o.ctx != nil && o.ctx.Err() != nil
+}
 
-const (
-	// BestEffort class when no resource requests or limits are specified.
-	BestEffort PodQosPolicy = "BestEffort"
+// collect lists the pods and runs the optional lookups on the result
+func collect(clientset kubernetes.Interface, namespace string, opts collectOptions) ([]podqos.PodData, error) {
+	if opts.checkNamespace && namespace != "" && opts.podName == "" {
+		if err := checkNamespace(opts.context(), clientset, namespace); err != nil {
+			return nil, err
+		}
+	}
+	var podData []podqos.PodData
+	var truncated bool
+	var err error
+	if opts.podName != "" {
+		podData, err = podqos.CollectPod(clientset, namespace, opts.podName)
+	} else {
+		// a list of every namespace is a single call paging through
+		// chunks, collectNamespaces reports its namespaces instead
+		var onPage func(listed, remaining int)
+		if namespace == "" {
+			onPage = opts.progress.page
+		}
+		podData, truncated, err = podqos.CollectPodsWithProgress(opts.context(), clientset, namespace, opts.fieldSelector, opts.maxPods, onPage)
+	}
+	if err != nil {
+		if opts.interrupted() {
+			return podData, err
+		}
+		return nil, err
+	}
+	if truncated {
+		logger.warnf("output truncated to %d pods, raise --max-pods to see more", opts.maxPods)
+	}
+	// the lookups are skipped once interrupted, the pods are shown without
+	ctx := opts.context()
+	if err := ctx.Err(); err != nil {
+		return podData, err
+	}
+	// interrupted during a lookup, the pods are still shown without what was
+	// left to look up
+	failed := func(err error) ([]podqos.PodData, error) {
+		if opts.interrupted() {
+			return podData, err
+		}
+		return nil, err
+	}
+	if opts.withHPA {
+		if err := annotateHPA(ctx, clientset, namespace, podData, opts.ownerCache); err != nil {
+			return failed(err)
+		}
+	}
+	nodes := opts.nodes
+	if nodes == nil {
+		nodes = newNodeLookup(clientset)
+	}
+	if opts.withNodeStatus {
+		if err := annotateNodeStatus(ctx, nodes, podData); err != nil {
+			return failed(err)
+		}
+	}
+	if opts.withNodeAllocatable {
+		if err := annotateNodeAllocatable(ctx, nodes, podData); err != nil {
+			return failed(err)
+		}
+	}
+	if opts.withOS {
+		if err := annotateOS(ctx, nodes, podData); err != nil {
+			return failed(err)
+		}
+	}
+	if opts.withNodePressure {
+		if err := annotateNodePressure(ctx, nodes, podData); err != nil {
+			return failed(err)
+		}
+	}
+	if opts.metrics != nil {
+		if err := annotateUsage(ctx, opts.metrics, namespace, podData); err != nil {
+			return failed(err)
+		}
+	}
+	return podData, nil
+}
 
-	// Burstable class when requests are less then limits
-	Burstable PodQosPolicy = "Burstable"
+// resolveNamespace picks the namespace to query, the -n flag wins over the
+// context namespace, and -A overrides both
+func resolveNamespace(contextNamespace, namespaceFlag string, allNameSpaces bool) string {
+	// if the -A flag is set use empty string
+	if allNameSpaces {
+		return ""
+	}
+	// if the flag is set return the value given as a flag
+	if namespaceFlag != "" {
+		return namespaceFlag
+	}
+	if contextNamespace == "" {
+		return "default"
+	}
+	return contextNamespace
+}
 
-	// Guaranteed class when requests are equal to limits
-	Guaranteed PodQosPolicy = "Guaranteed"
-)
+// contextNamespace is the namespace of the current context, empty when
+// there is no current context, e.g. a kubeconfig without one when the
+// server and token come from flags, or no kubeconfig with --from-file
+func contextNamespace(clientCfg *clientcmdapi.Config) string {
+	if clientCfg == nil {
+		return ""
+	}
+	if ctx, ok := clientCfg.Contexts[clientCfg.CurrentContext]; ok && ctx != nil {
+		return ctx.Namespace
+	}
+	return ""
+}
 
-// check if the kubeconfig is set, if not use default
-func getKubeConfig() *string {
-	env, ok := os.LookupEnv("KUBECONFIG")
-	if !ok {
-		temp := "~/.kube/config"
-		return &temp
+// loadKubeconfig reads the kubeconfig from KUBECONFIG or the default path, a
+// malformed file is reported as such rather than failing later on
+func loadKubeconfig() (*clientcmdapi.Config, error) {
+	clientCfg, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
 	}
-	return &env
+	return clientCfg, nil
 }
 
-func (c *ContainerData) getQosClass() PodQosPolicy {
+// clusterFlags connect straight to a server with a token instead of going
+// through kubeconfig, for CI jobs that only have a service account token
+type clusterFlags struct {
+	server               string
+	token                string
+	certificateAuthority string
+	// jsonProtocol talks json to the api server instead of protobuf
+	jsonProtocol bool
+	// qps and burst rate limit the requests of every client
+	qps   float32
+	burst int
+}
 
-	if c.Limits.CPU.MilliValue() == 0 && c.Requests.CPU.MilliValue() == 0 {
-		return BestEffort
+// restConfig builds the config from the flags, nil when no server is given
+func (f clusterFlags) restConfig() (*rest.Config, error) {
+	if f.server == "" {
+		if f.token != "" || f.certificateAuthority != "" {
+			return nil, fmt.Errorf("--token and --certificate-authority need --server")
+		}
+		return nil, nil
 	}
-	if c.Limits.CPU.MilliValue() == c.Requests.CPU.MilliValue() {
-		return Guaranteed
+	return &rest.Config{
+		Host:            f.server,
+		BearerToken:     f.token,
+		TLSClientConfig: rest.TLSClientConfig{CAFile: f.certificateAuthority},
+	}, nil
+}
+
+// newClientset builds a client from the flags when --server is set, and
+// otherwise for the current context in kubeconfig. The deferred loader
+// follows KUBECONFIG and the default path the same way kubectl does and runs
+// exec credential plugins and auth providers, which EKS, GKE and AKS
+// clusters need
+func (f clusterFlags) newClientset() (kubernetes.Interface, error) {
+	config, err := f.clientConfig()
+	if err != nil {
+		return nil, err
 	}
-	if c.Requests.CPU.MilliValue() < c.Limits.CPU.MilliValue(){
-		return Burstable
+	if f.jsonProtocol {
+		useJSON(config)
+	} else {
+		useProtobuf(config)
 	}
-	return BestEffort
+	return podqos.NewClient(config)
 }
 
-func main() {
-	var namespaceFlag = flag.String("n", "", "sets the namespace for the api request")
-	allNameSpaces := flag.Bool("A", false, "Query all namespaces")
-	flag.Parse()
-	clientCfg, _ := clientcmd.NewDefaultClientConfigLoadingRules().Load()
-	namespace := clientCfg.Contexts[clientCfg.CurrentContext].Namespace
+// useProtobuf has the core clients request protobuf, listing thousands of
+// pods is a lot less to send and decode than json. Only built in types
+// have a protobuf form, so the metrics client is left on json
+func useProtobuf(config *rest.Config) {
+	config.ContentType = runtime.ContentTypeProtobuf
+	config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+}
 
-	// if the flag is set return the value given as a flag
-	if *namespaceFlag != "" {
-		namespace = *namespaceFlag
+// useJSON has the clients talk json only. It has to be set explicitly, the
+// generated clients ask for protobuf whenever no content type is configured
+func useJSON(config *rest.Config) {
+	config.ContentType = runtime.ContentTypeJSON
+	config.AcceptContentTypes = runtime.ContentTypeJSON
+}
+
+// clientConfig is the config newClientset connects with
+func (f clusterFlags) clientConfig() (*rest.Config, error) {
+	config, err := f.restConfig()
+	if err == nil && config == nil {
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(),
+			&clientcmd.ConfigOverrides{},
+		).ClientConfig()
+	}
+	if err != nil {
+		return nil, err
 	}
+	f.throttle(config)
+	return config, nil
+}
 
-	if namespace == "" {
-		namespace = "default"
+// throttle applies --qps and --burst, so a scan of a big cluster with owner
+// and node lookups doesn't crowd out other clients under API priority and
+// fairness
+func (f clusterFlags) throttle(config *rest.Config) {
+	config.QPS = f.qps
+	config.Burst = f.burst
+}
+
+// tableOptions controls the optional columns of the container table
+type tableOptions struct {
+	// showContext prefixes each row with the kubeconfig context
+	showContext bool
+	// labelColumns adds a column per label key, like kubectl -L
+	labelColumns []string
+	// annotationColumns adds a column per annotation key
+	annotationColumns []string
+	// showHPA adds the autoscaler of the owning workload
+	showHPA bool
+	// resources picks the limit/request columns and what the class is computed from
+	resources []v1.ResourceName
+	// showHasLimits adds whether cpu and memory limits are set at all
+	showHasLimits bool
+	// quantityStyle is how quantities are rendered
+	quantityStyle format.Style
+	// showNodeStatus adds the node and whether it is cordoned or tainted
+	showNodeStatus bool
+	// grouped prints each pod once with its containers indented beneath it
+	grouped bool
+	// compactNamespaces prints each namespace once as a section header
+	// instead of a NAMESPACE column
+	compactNamespaces bool
+	// showPercentOfNode adds the cpu and memory requests as a share of the node
+	showPercentOfNode bool
+	// maxRows caps the number of container rows, zero is unlimited
+	maxRows int
+	// showOS adds the operating system the pod runs on
+	showOS bool
+	// markdown renders the table as GitHub flavored markdown
+	markdown bool
+	// visual adds a bar per resource of how much of the limit is requested
+	visual bool
+	// asciiOnly replaces non ascii runes in names with a placeholder
+	asciiOnly bool
+	// shortClass prints the class as a single letter
+	shortClass bool
+	// showNodePressure adds the pressure conditions of the node
+	showNodePressure bool
+	// showTemplateHash adds the pod-template-hash label
+	showTemplateHash bool
+	// groupByTemplateHash prints a header per ReplicaSet revision with its
+	// pods beneath it
+	groupByTemplateHash bool
+	// pricing adds the estimated monthly cost of each container's requests
+	pricing *Pricing
+	// showUsage adds the cpu and memory the container currently uses, also as
+	// a share of its requests
+	showUsage bool
+	// showProbes adds whether liveness and readiness probes are set
+	showProbes bool
+	// showType lists init and ephemeral containers too, with a TYPE column
+	// telling them apart
+	showType bool
+	// showPriority adds the priority class of the pod, preemption and
+	// eviction look at it along with the class
+	showPriority bool
+	// showRestarts adds how often each container restarted
+	showRestarts bool
+	// showStatusClass adds the computed class of the pod next to the class
+	// kubelet reported in its status
+	showStatusClass bool
+	// showClaims adds the dynamic resource allocation claims of each container
+	showClaims bool
+	// tsv writes the table tab separated without padding
+	tsv bool
+	// noHeaders leaves out the header line of the table and tsv
+	noHeaders bool
+}
+
+// classCell is the class as printed in the table
+func classCell(class podqos.PodQosPolicy, opts tableOptions) string {
+	if opts.shortClass {
+		return shortClasses[class]
 	}
+	return string(class)
+}
 
-	// if the -A flag is set use empty string
-	if *allNameSpaces {
-		namespace = ""
+// displayName is a namespace, pod or container name as printed in the table
+func displayName(name string, opts tableOptions) string {
+	if opts.asciiOnly {
+		return asciiOnly(name)
 	}
+	return name
+}
 
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", *getKubeConfig())
-	if err != nil {
-		panic(err.Error())
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
+	return list
+}
 
-	// create the clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
+// noneIfEmpty shows "<none>" for empty values like kubectl does
+func noneIfEmpty(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// columnHeader turns a label or annotation key into a column header the way
+// kubectl does, e.g. app.kubernetes.io/name becomes NAME
+func columnHeader(key string) string {
+	return strings.ToUpper(path.Base(key))
+}
+
+// resourceHeader is the column prefix for a resource, e.g. CPU for cpu and
+// GPU for nvidia.com/gpu
+func resourceHeader(name v1.ResourceName) string {
+	switch name {
+	case v1.ResourceCPU:
+		return "CPU"
+	case v1.ResourceMemory:
+		return "MEM"
+	}
+	return columnHeader(string(name))
+}
+
+// quantityCell shows "<none>" for a resource that isn't set, so it can be
+// told apart from an explicit 0
+func quantityCell(style format.Style, r podqos.ResourceData, name v1.ResourceName) string {
+	if !r.Has(name) {
+		return "<none>"
+	}
+	return format.Resource(style, name, r.Get(name))
+}
+
+// podHeader is the header of the pod columns that start every row
+func podHeader(opts tableOptions) []string {
+	header := []string{"NAMESPACE", "POD NAME"}
+	if opts.showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	return header
+}
+
+// podCells are the pod columns that start every row
+func podCells(v podqos.PodData, opts tableOptions) []string {
+	row := []string{displayName(v.NameSpace, opts), displayName(v.PodName, opts)}
+	if opts.showContext {
+		row = append([]string{v.Context}, row...)
+	}
+	return row
+}
+
+// tableResources is the resources shown, defaulting to cpu
+func tableResources(opts tableOptions) []v1.ResourceName {
+	if len(opts.resources) == 0 {
+		return podqos.DefaultResources
+	}
+	return opts.resources
+}
+
+// containerHeader is the header of the container columns
+func containerHeader(opts tableOptions) []string {
+	header := []string{"CONTAINER"}
+	if opts.showType {
+		header = append(header, "TYPE")
+	}
+	for _, name := range tableResources(opts) {
+		header = append(header, resourceHeader(name)+"l", resourceHeader(name)+"r")
+	}
+	if opts.showUsage {
+		header = append(header, "USED-CPU", "USED-MEM", "CPU%REQ", "MEM%REQ")
+	}
+	if opts.visual {
+		for _, name := range tableResources(opts) {
+			header = append(header, resourceHeader(name)+"-FILL")
+		}
+	}
+	header = append(header, "CLASS")
+	for _, key := range opts.labelColumns {
+		header = append(header, columnHeader(key))
+	}
+	for _, key := range opts.annotationColumns {
+		header = append(header, columnHeader(key))
+	}
+	if opts.showHPA {
+		header = append(header, "HPA")
+	}
+	if opts.showHasLimits {
+		header = append(header, "HAS-CPU-LIMIT", "HAS-MEM-LIMIT")
+	}
+	if opts.showNodeStatus {
+		header = append(header, "NODE", "NODE-STATUS")
+	}
+	if opts.showPercentOfNode {
+		header = append(header, "CPUr%NODE", "MEMr%NODE")
+	}
+	if opts.showOS {
+		header = append(header, "OS")
+	}
+	if opts.showNodePressure {
+		header = append(header, "NODE-PRESSURE")
+	}
+	if opts.showTemplateHash {
+		header = append(header, "TEMPLATE-HASH")
+	}
+	if opts.pricing != nil {
+		header = append(header, "COST/MO")
+	}
+	if opts.showProbes {
+		header = append(header, "LIVENESS", "READINESS")
+	}
+	if opts.showPriority {
+		header = append(header, "PRIORITY")
+	}
+	if opts.showRestarts {
+		header = append(header, "RESTARTS")
+	}
+	if opts.showStatusClass {
+		header = append(header, "POD-CLASS", "STATUS-CLASS")
+	}
+	if opts.showClaims {
+		header = append(header, "CLAIMS")
+	}
+	return header
+}
+
+// containerCells are the container columns of a row
+func containerCells(v podqos.PodData, c podqos.ContainerData, opts tableOptions) []string {
+	resources := tableResources(opts)
+	row := []string{displayName(c.Name, opts)}
+	if opts.showType {
+		row = append(row, containerType(v, c.Name))
+	}
+	for _, name := range resources {
+		row = append(row, quantityCell(opts.quantityStyle, c.Limits, name), quantityCell(opts.quantityStyle, c.Requests, name))
+	}
+	if opts.showUsage {
+		row = append(row,
+			quantityCell(opts.quantityStyle, c.Usage, v1.ResourceCPU), quantityCell(opts.quantityStyle, c.Usage, v1.ResourceMemory),
+			usageOfRequest(c, v1.ResourceCPU), usageOfRequest(c, v1.ResourceMemory))
+	}
+	if opts.visual {
+		for _, name := range resources {
+			row = append(row, fillBar(c, name, barWidth))
+		}
+	}
+	row = append(row, classCell(c.QosClass(resources), opts))
+	// a missing label or annotation leaves the column empty
+	for _, key := range opts.labelColumns {
+		row = append(row, v.Labels[key])
+	}
+	for _, key := range opts.annotationColumns {
+		row = append(row, v.Annotations[key])
+	}
+	if opts.showHPA {
+		row = append(row, noneIfEmpty(v.HPA))
+	}
+	if opts.showHasLimits {
+		row = append(row, strconv.FormatBool(c.Limits.Has(v1.ResourceCPU)), strconv.FormatBool(c.Limits.Has(v1.ResourceMemory)))
+	}
+	if opts.showNodeStatus {
+		row = append(row, noneIfEmpty(v.NodeName), noneIfEmpty(v.NodeStatus))
+	}
+	if opts.showPercentOfNode {
+		row = append(row,
+			percentOfNode(c.Requests.CPU(), v.NodeAllocatable, v1.ResourceCPU),
+			percentOfNode(c.Requests.Memory(), v.NodeAllocatable, v1.ResourceMemory))
+	}
+	if opts.showOS {
+		row = append(row, v.OS)
+	}
+	if opts.showNodePressure {
+		row = append(row, pressureCell(v.NodePressure, c.QosClass(resources)))
+	}
+	if opts.showTemplateHash {
+		row = append(row, noneIfEmpty(v.Labels[appsv1.DefaultDeploymentUniqueLabelKey]))
+	}
+	if opts.pricing != nil {
+		row = append(row, formatCost(opts.pricing.monthly(c.Requests)))
+	}
+	if opts.showProbes {
+		row = append(row, probeCells(v, c.Name)...)
+	}
+	if opts.showPriority {
+		row = append(row, priorityCell(v))
+	}
+	if opts.showRestarts {
+		row = append(row, restartsCell(v, c.Name))
+	}
+	if opts.showStatusClass {
+		row = append(row, statusClassCells(v)...)
+	}
+	if opts.showClaims {
+		row = append(row, claimsCell(v, c.Name))
+	}
+	return row
+}
+
+// noContainers is the row shown for a pod without containers, which a
+// malformed or mirror pod can have
+const noContainers = "<no containers>"
+
+// withPlaceholders returns the pods with a single noContainers row for the
+// pods that have no containers, so they aren't left out of the table. Like
+// kubelet, the placeholder without resources makes the pod BestEffort
+func withPlaceholders(podData []podqos.PodData) []podqos.PodData {
+	shown := make([]podqos.PodData, len(podData))
+	for i, p := range podData {
+		if len(p.Containers) == 0 {
+			p.Containers = []podqos.ContainerData{{Name: noContainers}}
+		}
+		shown[i] = p
+	}
+	return shown
+}
+
+// capRows keeps the first maxRows container rows, the last pod kept may
+// lose some of its containers. hidden is how many rows were dropped
+func capRows(podData []podqos.PodData, maxRows int) (shown []podqos.PodData, hidden int) {
+	rows := 0
+	for _, v := range podData {
+		if rows >= maxRows {
+			hidden += len(v.Containers)
+			continue
+		}
+		if rows+len(v.Containers) > maxRows {
+			keep := maxRows - rows
+			hidden += len(v.Containers) - keep
+			v.Containers = v.Containers[:keep]
+		}
+		rows += len(v.Containers)
+		shown = append(shown, v)
+	}
+	return shown, hidden
+}
+
+// printTable writes one row per container, stopping after opts.maxRows with
+// a footer saying how many were left out
+func printTable(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	if opts.showType {
+		podData = withAllContainers(podData)
+	}
+	podData = withPlaceholders(podData)
+	hidden := 0
+	if opts.maxRows > 0 {
+		podData, hidden = capRows(podData, opts.maxRows)
+	}
+	switch {
+	case opts.markdown:
+		printMarkdown(w, podData, opts)
+	case opts.tsv:
+		printTSV(w, podData, opts)
+	case opts.groupByTemplateHash:
+		printTemplateHashGroups(w, podData, opts)
+	case opts.compactNamespaces:
+		printNamespaceSections(w, podData, opts)
+	case opts.grouped:
+		printGrouped(w, podData, opts)
+	default:
+		printFlat(w, podData, opts)
+	}
+	if hidden > 0 {
+		fmt.Fprintf(w, "... and %d more, use -o json for all\n", hidden)
+	}
+}
+
+// printFlat writes one row per container with the pod columns repeated
+func printFlat(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if !opts.noHeaders {
+		fmt.Fprintln(tw, strings.Join(append(podHeader(opts), containerHeader(opts)...), "\t"))
 	}
-	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		panic(err.Error())
-	}
-	var podData []PodData
-	// loop through the pods, and for each pod get the resources
-	for _, pod := range pods.Items { // don't forget _ is there to ignore the index of the list
-		var containers []ContainerData
-		for _, container := range pod.Spec.Containers {
-			containers = append(containers, ContainerData{
-				Name: container.Name,
-				Limits: ResourceData{
-					CPU: container.Resources.Limits.Cpu(),
-					Memory: container.Resources.Limits.Memory(),
-				},
-				Requests: ResourceData{
-					CPU: container.Resources.Requests.Cpu(),
-					Memory: container.Resources.Requests.Memory(),
-				},
-
-			})
-		}
-		podData = append(podData, PodData{
-			PodName: pod.Name,
-			NameSpace: pod.Namespace,
-			Containers: containers,
-		})
-	}
-	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(tw, "NAMESPACE\tPOD NAME\tCONTAINER\tCPUl\tCPUr\tCLASS")
 	for _, v := range podData {
 		for _, c := range v.Containers {
-			fmt.Fprintln(tw, strings.Join([]string{v.NameSpace, v.PodName, c.Name, c.Limits.CPU.String(), c.Requests.CPU.String(), string(c.getQosClass())}, "\t"))
+			fmt.Fprintln(tw, strings.Join(append(podCells(v, opts), containerCells(v, c, opts)...), "\t"))
+		}
+	}
+	tw.Flush()
+}
 
+// printGrouped writes each pod once as a header line with its containers
+// indented beneath it, which reads better for multi-container pods
+func printGrouped(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	// align every container row as one table first, then put the pod lines
+	// in between so they don't break up the columns
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(containerHeader(opts), "\t"))
+	for _, v := range podData {
+		for _, c := range v.Containers {
+			fmt.Fprintln(tw, strings.Join(containerCells(v, c, opts), "\t"))
 		}
 	}
 	tw.Flush()
-}
\ No newline at end of file
+	lines := strings.Split(buf.String(), "\n")
+
+	if !opts.noHeaders {
+		fmt.Fprintln(w, "  "+lines[0])
+	}
+	next := 1
+	for _, v := range podData {
+		fmt.Fprintln(w, strings.Join(podCells(v, opts), "/"))
+		for range v.Containers {
+			fmt.Fprintln(w, "  "+lines[next])
+			next++
+		}
+	}
+}
+
+// runList is the list command, and get when getPod is set, which takes
+// exactly one pod name
+func runList(args []string, getPod bool) {
+	var namespaceFlag = flag.String("n", "", "sets the namespace for the api request")
+	allNameSpaces := flag.Bool("A", false, "Query all namespaces")
+	excludeSystem := flag.Bool("exclude-system", false, "With -A, leave out "+strings.Join(systemNamespaces, ", "))
+	excludeNamespacesFlag := flag.String("exclude-namespaces", "", "With -A, comma separated namespaces to leave out, replaces the system ones of --exclude-system")
+	includeNamespacesFlag := flag.String("include-namespaces", "", "With -A, comma separated namespaces to query, --exclude-namespaces wins over it")
+	summary := flag.Bool("summary", false, "Print per namespace totals instead of one row per container")
+	totalsByOwner := flag.Bool("resource-totals-by-owner", false, "Print the total requests and limits of each workload across its pods, largest cpu request first")
+	burstHeadroomFlag := flag.Bool("burst-headroom", false, "Print per namespace how far the Burstable pods could burst above their requests")
+	allContexts := flag.Bool("all-contexts", false, "Query every context in the kubeconfig")
+	contextPrefix := flag.String("context-prefix", "", "With --all-contexts, label each context with the first capture group of this regex, e.g. 'cluster/(.+)'")
+	var labelColumns string
+	flag.StringVar(&labelColumns, "L", "", "Comma separated list of labels to show as columns")
+	flag.StringVar(&labelColumns, "label-columns", "", "Comma separated list of labels to show as columns")
+	annotationColumns := flag.String("annotation-columns", "", "Comma separated list of annotations to show as columns")
+	effective := flag.Bool("effective", false, "Print the scheduler-effective requests and limits per pod, accounting for init containers")
+	resources := flag.String("resources", "cpu", "Comma separated list of resources to show and compute the class from, e.g. cpu,memory,nvidia.com/gpu")
+	withHPA := flag.Bool("with-hpa", false, "Show the HorizontalPodAutoscaler scaling each pod's workload")
+	var output string
+	outputHelp := "Output format, one of: " + strings.Join(outputFormats, ", ")
+	flag.StringVar(&output, "o", "", outputHelp)
+	flag.StringVar(&output, "output", "", outputHelp)
+	scale := flag.Float64("scale", 1, "Multiply every displayed quantity by this factor, e.g. 3 to project a 3x replica scale-out")
+	baseline := flag.String("baseline", "", "Compare against a report saved with -o json and print what changed")
+	maxPods := flag.Int("max-pods", 0, "Stop after collecting this many pods, 0 means no limit")
+	showHasLimits := flag.Bool("show-has-limits", false, "Show whether cpu and memory limits are set at all, an explicit 0 counts as set")
+	rawQuantities := flag.Bool("raw-quantities", false, "Show quantities with the suffix they were set with, same as --format-quantities raw")
+	only := flag.String("only", "", "Only show containers matching all of the comma separated predicates: "+strings.Join(onlyKeys(), ", "))
+	containerSelector := flag.String("container-selector", "", "Only show containers matching the selector over their computed fields class, hasLimit, hasRequest and name, e.g. class=Guaranteed,hasLimit=true")
+	outputVersion := flag.String("output-version", "v1alpha1", "Version of the report -o json and yaml write, one of: "+strings.Join(outputVersions, ", "))
+	formatQuantities := flag.String("format-quantities", "human", "How table quantities are rendered, one of: raw, human, scientific. json and yaml always use the canonical form")
+	withNodePressure := flag.Bool("node-pressure", false, "Show the memory, disk and pid pressure of each pod's node, marking BestEffort pods on such nodes as at risk")
+	withNodeStatus := flag.Bool("with-node-status", false, "Show each pod's node and whether it is cordoned or has NoSchedule taints")
+	compact := flag.Bool("compact", false, "With -o json, print each document on a single line instead of indented")
+	kubectlCompat := flag.Bool("kubectl-compat", false, "With -o json, print a kubectl style List of the pods annotated with their class")
+	sortBy := flag.String("sort-by", "", "Sort pods, biggest or most likely evicted first, one of: "+strings.Join(sortKeys, ", "))
+	top := flag.Int("top", 0, "With --sort-by, only show the first N pods, the biggest consumers")
+	grouped := flag.Bool("grouped", false, "Print each pod once with its containers indented beneath it")
+	groupBy := flag.String("group-by", "", "Group the table, one of: "+strings.Join(groupByKeys, ", ")+". pod is the same as --grouped")
+	compactNamespaces := flag.Bool("compact-namespaces", false, "Print each namespace once as a section header instead of a NAMESPACE column, for -A")
+	showTemplateHash := flag.Bool("show-template-hash", false, "Show the pod-template-hash label, which tells the ReplicaSets of a rollout apart")
+	containerSort := flag.String("container-sort", "", "Sort the containers within each pod, one of: "+strings.Join(containerSortKeys, ", "))
+	quotaHeadroomFlag := flag.Bool("resource-quota-headroom", false, "Print whether one more Guaranteed pod of --probe-size fits in the namespace ResourceQuotas, exit 1 when it doesn't")
+	probeSize := flag.String("probe-size", "", "Size of the pod --resource-quota-headroom checks, e.g. cpu=500m,memory=256Mi")
+	withQuota := flag.Bool("with-quota", false, "Also print how much of the namespace ResourceQuotas for cpu and memory is used")
+	percentOfNodeFlag := flag.Bool("percent-of-node", false, "Show each container's cpu and memory requests as a percentage of its node's allocatable")
+	maxRows := flag.Int("max-rows", 0, "Only print this many table rows followed by a count of the rest, 0 means no limit")
+	showSummaryFooter := flag.Bool("show-summary-footer", false, "End the table with a tally of pods per class, only when printing to a terminal")
+	minCPU := flag.String("min-cpu", "", "Only show pods whose effective cpu request is at least this, e.g. 500m")
+	maxCPU := flag.String("max-cpu", "", "Only show pods whose effective cpu request is at most this, e.g. 2")
+	minMemory := flag.String("min-memory", "", "Only show pods whose effective memory request is at least this, e.g. 256Mi")
+	maxMemory := flag.String("max-memory", "", "Only show pods whose effective memory request is at most this, e.g. 4Gi")
+	namespaceSelector := flag.String("namespace-selector", "", "Query every namespace whose labels match this selector, e.g. team=payments")
+	namespaceFile := flag.String("namespace-file", "", "Query every namespace listed in this file, one per line")
+	outputFile := flag.String("output-file", "", "Write the report to this file instead of stdout, gzipped when it ends in .gz")
+	gzipFlag := flag.Bool("gzip", false, "Gzip the --output-file whatever its name")
+	watchBuffer := flag.Int("watch-buffer", 100, "How many watch events can queue up while rows are printed")
+	visual := flag.Bool("visual", false, "Draw a bar of how much of its limit each container requests, only when printing to a terminal")
+	asciiOnlyFlag := flag.Bool("ascii-only", false, "Replace non ascii characters in namespace, pod and container names with ?")
+	shortClass := flag.Bool("short-class", false, "Print the class as G, B or E with a key after the table, for wide tables")
+	costFlag := flag.Bool("cost", false, "Estimate the monthly cost of each container's requests and total it per namespace, needs --pricing-file")
+	pricingFile := flag.String("pricing-file", "", "YAML file with cpuCoreHour and memoryGiBHour prices for --cost")
+	withUsage := flag.Bool("with-usage", false, "Show the cpu and memory each container uses now, from metrics-server")
+	goCPU := flag.Bool("go-cpu-check", false, "Warn about containers that look like go programs, a heuristic on image and labels, with a fractional cpu limit and no GOMAXPROCS. They may be throttled if built with Go < 1.25")
+	useAnnotations := flag.Bool("use-annotations", false, "Prefer the class in the --class-annotation of a pod, a class name or cgroup path, over the computed one")
+	classAnnotationKey := flag.String("class-annotation", classAnnotation, "With --use-annotations, the annotation holding the realized class of the pod")
+	noHeaders := flag.Bool("no-headers", false, "Leave out the header line of the table and -o tsv")
+	showRestarts := flag.Bool("show-restarts", false, "Show how often each container restarted")
+	showStatusClass := flag.Bool("show-status-class", false, "Show the class of each pod computed from cpu and memory, like kubelet does, next to the class in its status")
+	onlyMismatches := flag.Bool("only-mismatches", false, "Only show pods whose computed class differs from the class in their status, implies --show-status-class. The class is computed from cpu and memory the way kubelet does, whatever --resources is")
+	preset := flag.String("preset", "", "Turn on a named set of flags, one of: "+strings.Join(presetNames(), ", "))
+	showPriority := flag.Bool("show-priority", false, "Show the priority class of each pod and the priority it resolved to")
+	showType := flag.Bool("show-type", false, "Also list init and ephemeral containers, with a TYPE column telling them apart")
+	showProbes := flag.Bool("show-probes", false, "Show whether each container has liveness and readiness probes")
+	showClaims := flag.Bool("show-claims", false, "Show the resource claims of each container, devices allocated through dynamic resource allocation")
+	explainEviction := flag.Bool("explain-eviction", false, "After the table, describe when kubelet would evict each pod given its class")
+	showLegend := flag.Bool("legend", false, "Print a key explaining the classes and their eviction order after the table")
+	fromFile := flag.String("from-file", "", "Compute the classes of the pods and workloads in a manifest instead of a cluster, - reads stdin")
+	workload := flag.String("workload", "", "Compute the class of a workload's pod template instead of its pods, e.g. deployment/web")
+	cacheDir := flag.String("cache-dir", "", "Keep owner lookups in this directory so repeated runs make fewer api calls")
+	cacheTTL := flag.Duration("cache-ttl", time.Hour, "How long entries in --cache-dir stay valid")
+	skipNamespaceCheck := flag.Bool("skip-namespace-check", false, "Don't check the namespace exists first, for users who can list pods but not get namespaces")
+	logFormat := flag.String("log-format", "text", "Format of warnings on stderr, one of: "+strings.Join(logFormats, ", "))
+	quiet := flag.Bool("quiet", false, "Don't print scanning progress to stderr, it is only printed to a terminal anyway")
+	auditPolicy := flag.String("audit-policy", "", "Check pods against the minimum classes in this YAML policy and exit 1 on violations, the class is computed from cpu and memory like kubelet does")
+	junit := flag.String("junit", "", "With --audit-policy, also write the audit to this file as JUnit XML, a test per pod and a failure per violation")
+	showOS := flag.Bool("show-os", false, "Show the operating system each pod runs on, from its node selector or its node, linux when neither says")
+	eventsLog := flag.String("events-log", "", "With --watch, append every event printed to this file as a json line")
+	var watchFlag bool
+	flag.BoolVar(&watchFlag, "w", false, "Watch for changes and print a row whenever a container's class changes")
+	flag.BoolVar(&watchFlag, "watch", false, "Watch for changes and print a row whenever a container's class changes")
+	watchTimeout := flag.Duration("watch-timeout", 0, "Stop watching after this long and print a summary, e.g. 5m. 0 watches forever")
+	waitFor := flag.String("wait-for-class", "", "Wait until the named pod has this class and exit 0, or exit 1 after --timeout")
+	timeout := flag.Duration("timeout", 0, "How long --wait-for-class waits, e.g. 5m. 0 waits forever")
+	var cluster clusterFlags
+	flag.StringVar(&cluster.server, "server", "", "Address of the api server, to connect without a kubeconfig")
+	flag.StringVar(&cluster.token, "token", "", "Bearer token to authenticate to --server with")
+	var qps float64
+	flag.Float64Var(&qps, "qps", 20, "Most requests per second to send to the api server")
+	flag.IntVar(&cluster.burst, "burst", 40, "Most requests to send to the api server in a burst above --qps")
+	flag.BoolVar(&cluster.jsonProtocol, "json-protocol", false, "Talk json to the api server instead of protobuf, for proxies that only pass json")
+	flag.StringVar(&cluster.certificateAuthority, "certificate-authority", "", "CA certificate file to verify --server with")
+	serveAddr := flag.String("serve", "", "Serve the report over http on the given address, e.g. :8080")
+	setUsage(flag.CommandLine)
+	flag.CommandLine.Parse(args)
+	if getPod && flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kubectl podqos get <pod> [flags]")
+		os.Exit(1)
+	}
+	if *preset != "" {
+		if err := applyPreset(flag.CommandLine, *preset); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if qps <= 0 || cluster.burst < 1 {
+		fmt.Fprintln(os.Stderr, "--qps and --burst need to be above 0")
+		os.Exit(1)
+	}
+	cluster.qps = float32(qps)
+	if *workload != "" && !strings.Contains(*workload, "/") {
+		fmt.Fprintf(os.Stderr, "invalid --workload %q, expected kind/name e.g. deployment/web\n", *workload)
+		os.Exit(1)
+	}
+	var probe podqos.ResourceData
+	if *quotaHeadroomFlag {
+		if *allNameSpaces || *allContexts || *fromFile != "" {
+			fmt.Fprintln(os.Stderr, "--resource-quota-headroom checks the quotas of a single namespace, it can't be combined with -A, --all-contexts or --from-file")
+			os.Exit(1)
+		}
+		var err error
+		if probe, err = parseProbeSize(*probeSize); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *junit != "" && *auditPolicy == "" {
+		fmt.Fprintln(os.Stderr, "--junit writes the audit, it needs --audit-policy")
+		os.Exit(1)
+	}
+	if *scale <= 0 {
+		fmt.Fprintln(os.Stderr, "--scale needs to be above 0")
+		os.Exit(1)
+	}
+	if *scale != 1 && ((output != "" && output != "markdown") || *baseline != "" || *auditPolicy != "" || watchFlag) {
+		fmt.Fprintln(os.Stderr, "--scale only projects the tables, it can't be combined with -o other than markdown, --baseline, --audit-policy or --watch")
+		os.Exit(1)
+	}
+	if *useAnnotations && (watchFlag || *waitFor != "") {
+		fmt.Fprintln(os.Stderr, "--use-annotations reads the collected pods, it can't be combined with --watch or --wait-for-class")
+		os.Exit(1)
+	}
+	if *eventsLog != "" && !watchFlag {
+		fmt.Fprintln(os.Stderr, "--events-log logs the watch, it needs --watch")
+		os.Exit(1)
+	}
+	if *fromFile != "" && (flag.NArg() > 0 || *workload != "" || *allNameSpaces || *allContexts || watchFlag || *waitFor != "" || *withQuota || *withUsage) {
+		fmt.Fprintln(os.Stderr, "--from-file only reads the manifest, it can't be combined with a pod name, --workload, -A, --all-contexts, --watch, --wait-for-class, --with-quota or --with-usage")
+		os.Exit(1)
+	}
+	if (flag.NArg() > 0 || *workload != "") && *allNameSpaces {
+		fmt.Fprintln(os.Stderr, "a pod or workload cannot be retrieved by name across all namespaces")
+		os.Exit(1)
+	}
+	if *waitFor != "" {
+		if _, ok := classRank[podqos.PodQosPolicy(*waitFor)]; !ok {
+			fmt.Fprintf(os.Stderr, "unsupported class %q, use Guaranteed, Burstable or BestEffort\n", *waitFor)
+			os.Exit(1)
+		}
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "--wait-for-class needs a pod name")
+			os.Exit(1)
+		}
+	}
+	if _, err := cluster.restConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var pricing *Pricing
+	if *costFlag {
+		if *pricingFile == "" {
+			fmt.Fprintln(os.Stderr, "--cost needs a --pricing-file")
+			os.Exit(1)
+		}
+		var err error
+		if pricing, err = loadPricing(*pricingFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if err := logger.setFormat(*logFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var thresholds []threshold
+	for _, t := range []struct {
+		name     v1.ResourceName
+		min, max string
+	}{{v1.ResourceCPU, *minCPU, *maxCPU}, {v1.ResourceMemory, *minMemory, *maxMemory}} {
+		min, err := parseThreshold("min-"+string(t.name), t.name, t.min)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		max, err := parseThreshold("max-"+string(t.name), t.name, t.max)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if min != nil || max != nil {
+			thresholds = append(thresholds, threshold{name: t.name, min: min, max: max})
+		}
+	}
+	onlyFilter, err := parseOnly(*only)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	containerFilter, err := parseContainerSelector(*containerSelector)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *top < 0 || (*top > 0 && *sortBy == "") {
+		fmt.Fprintln(os.Stderr, "--top needs a --sort-by to rank the pods by and a count above 0")
+		os.Exit(1)
+	}
+	if !validSortKey(*sortBy, sortKeys) {
+		fmt.Fprintf(os.Stderr, "unsupported sort key %q, allowed keys are: %s\n", *sortBy, strings.Join(sortKeys, ", "))
+		os.Exit(1)
+	}
+	if !validSortKey(*groupBy, groupByKeys) {
+		fmt.Fprintf(os.Stderr, "unsupported group by %q, allowed values are: %s\n", *groupBy, strings.Join(groupByKeys, ", "))
+		os.Exit(1)
+	}
+	if *compactNamespaces && (*grouped || *groupBy != "" || output == "markdown" || output == "tsv") {
+		fmt.Fprintln(os.Stderr, "--compact-namespaces is a table layout of its own, it can't be combined with --grouped, --group-by or -o markdown or tsv")
+		os.Exit(1)
+	}
+	if !validSortKey(*containerSort, containerSortKeys) {
+		fmt.Fprintf(os.Stderr, "unsupported container sort key %q, allowed keys are: %s\n", *containerSort, strings.Join(containerSortKeys, ", "))
+		os.Exit(1)
+	}
+	var contextPattern *regexp.Regexp
+	if *contextPrefix != "" {
+		var err error
+		contextPattern, err = regexp.Compile(*contextPrefix)
+		if err == nil && contextPattern.NumSubexp() == 0 {
+			err = fmt.Errorf("no capture group")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --context-prefix %q: %v\n", *contextPrefix, err)
+			os.Exit(1)
+		}
+	}
+	quantityStyle, err := format.ParseStyle(*formatQuantities)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *rawQuantities {
+		if quantityStyle != format.Human && quantityStyle != format.Raw {
+			fmt.Fprintf(os.Stderr, "--raw-quantities can't be combined with --format-quantities %s\n", quantityStyle)
+			os.Exit(1)
+		}
+		quantityStyle = format.Raw
+	}
+	if !validSortKey(*outputVersion, outputVersions) {
+		fmt.Fprintf(os.Stderr, "unsupported --output-version %q, allowed versions are: %s\n", *outputVersion, strings.Join(outputVersions, ", "))
+		os.Exit(1)
+	}
+	if !validOutput(output) {
+		fmt.Fprintf(os.Stderr, "unsupported output format %q, allowed formats are: %s\n", output, strings.Join(outputFormats, ", "))
+		os.Exit(1)
+	}
+	var jsonPath *jsonpath.JSONPath
+	if strings.HasPrefix(output, jsonPathPrefix) {
+		if jsonPath, err = parseJSONPath(strings.TrimPrefix(output, jsonPathPrefix)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var goTemplate *template.Template
+	if strings.HasPrefix(output, goTemplatePrefix) {
+		if goTemplate, err = parseGoTemplate(strings.TrimPrefix(output, goTemplatePrefix)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *serveAddr != "" {
+		if err := serve(*serveAddr, cluster.newClientset, parseResources(*resources)); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	// a manifest is checked without a cluster, there may be no kubeconfig
+	var clientCfg *clientcmdapi.Config
+	var clientset kubernetes.Interface
+	if *fromFile == "" {
+		if clientCfg, err = loadKubeconfig(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if clientset, err = cluster.newClientset(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	namespace := resolveNamespace(contextNamespace(clientCfg), *namespaceFlag, *allNameSpaces)
+	qosResources := parseResources(*resources)
+
+	out, err := openOutput(*outputFile, *gzipFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *outputFile, err)
+		}
+	}()
+
+	var ownerCache *ownerDiskCache
+	if *cacheDir != "" {
+		if ownerCache, err = loadOwnerDiskCache(*cacheDir, *cacheTTL); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		defer func() {
+			if err := ownerCache.save(); err != nil {
+				logger.warnf("failed to save the cache: %v", err)
+			}
+		}()
+	}
+	var metrics metricsclientset.Interface
+	if *withUsage && !*allContexts {
+		config, err := cluster.clientConfig()
+		if err == nil {
+			metrics, err = metricsclientset.NewForConfig(config)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	}
+	interrupt, stopInterrupt := interruptContext()
+	defer stopInterrupt()
+	collectOpts := collectOptions{
+		withHPA:             *withHPA,
+		maxPods:             *maxPods,
+		podName:             flag.Arg(0),
+		withNodeStatus:      *withNodeStatus,
+		withNodeAllocatable: *percentOfNodeFlag,
+		withOS:              *showOS,
+		progress:            newProgress(os.Stderr, *quiet),
+		checkNamespace:      !*skipNamespaceCheck,
+		ownerCache:          ownerCache,
+		withNodePressure:    *withNodePressure,
+		withUsage:           *withUsage,
+		metrics:             metrics,
+		ctx:                 interrupt,
+		cluster:             cluster,
+	}
+	if *waitFor != "" {
+		ctx := interrupt
+		if *timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+			defer cancel()
+		}
+		if err := waitForClass(ctx, clientset, namespace, collectOpts.podName, podqos.PodQosPolicy(*waitFor), qosResources); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "pod/%s is %s\n", collectOpts.podName, *waitFor)
+		return
+	}
+	if *quotaHeadroomFlag {
+		usages, err := collectQuotas(collectOpts.context(), clientset, namespace)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		if !printQuotaHeadroom(out, quotaHeadroom(usages, probe), quantityStyle) {
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	// with -A the namespaces are filtered before listing, excluded ones with
+	// a field selector and included ones by listing just those
+	var excluded, included []string
+	if *allNameSpaces {
+		included = splitList(*includeNamespacesFlag)
+		excluded = splitList(*excludeNamespacesFlag)
+		if len(excluded) == 0 && *excludeSystem {
+			excluded = systemNamespaces
+		}
+		if len(excluded) > 0 {
+			collectOpts.fieldSelector = excludeSelector(excluded)
+		}
+	}
+	if watchFlag {
+		// Ctrl-C ends the watch like --watch-timeout does, with the summary
+		ctx := interrupt
+		if *watchTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *watchTimeout)
+			defer cancel()
+		}
+		watchOpts := watchOptions{resources: qosResources, buffer: *watchBuffer, podName: collectOpts.podName}
+		if *eventsLog != "" {
+			f, err := os.OpenFile(*eventsLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				out.Close()
+				os.Exit(1)
+			}
+			defer f.Close()
+			watchOpts.eventsLog = f
+		}
+		changes, err := watchPods(ctx, out, clientset, namespace, watchOpts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "# observed %d class changes\n", changes)
+		return
+	}
+	var podData []podqos.PodData
+	switch {
+	case *fromFile != "":
+		podData, err = readManifests(*fromFile, os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	case *allContexts:
+		podData = collectAllContexts(clientCfg, *namespaceFlag, *allNameSpaces, collectOpts, contextPattern)
+	case *workload != "":
+		parts := strings.SplitN(*workload, "/", 2)
+		podData, err = podqos.CollectWorkload(clientset, namespace, parts[0], parts[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	case len(included) > 0:
+		podData, err = collectNamespaces(clientset, includeNamespaces(included, excluded), collectOpts)
+		if err != nil && !collectOpts.interrupted() {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	case *namespaceSelector != "":
+		namespaces, err := selectNamespaces(collectOpts.context(), clientset, *namespaceSelector)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		if len(namespaces) == 0 {
+			logger.warnf("no namespaces match selector %q", *namespaceSelector)
+		}
+		podData, err = collectNamespaces(clientset, namespaces, collectOpts)
+		if err != nil && !collectOpts.interrupted() {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	case *namespaceFile != "":
+		namespaces, err := readNamespaceFile(*namespaceFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		podData, err = collectNamespaces(clientset, namespaces, collectOpts)
+		if err != nil && !collectOpts.interrupted() {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	default:
+		podData, err = collect(clientset, namespace, collectOpts)
+		collectOpts.progress.finish()
+		if err != nil && !collectOpts.interrupted() {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+	}
+	if collectOpts.interrupted() {
+		logger.warnf("interrupted, showing the %d pods collected so far", len(podData))
+	}
+	disambiguateContainers(podData)
+	if *useAnnotations {
+		useAnnotatedClasses(podData, *classAnnotationKey)
+	}
+	if *goCPU {
+		goCPUCheck(podData)
+	}
+	if len(thresholds) > 0 {
+		podData = filterThresholds(podData, thresholds)
+	}
+	if predicates := append(onlyFilter, containerFilter...); len(predicates) > 0 {
+		podData = filterContainers(podData, predicates, qosResources)
+	}
+	if *onlyMismatches {
+		podData = filterMismatches(podData)
+	}
+	if *allNameSpaces {
+		sortByIdentity(podData)
+	}
+	sortPods(podData, *sortBy, qosResources)
+	podData = topPods(podData, *top)
+	sortContainers(podData, *containerSort)
+	if *baseline != "" {
+		old, err := loadReport(*baseline)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		printChanges(out, diffReports(old, newReport(podData, qosResources)), *allContexts)
+		return
+	}
+	if *auditPolicy != "" {
+		policy, err := loadPolicy(*auditPolicy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		violations := auditPods(policy, podData)
+		printViolations(out, violations)
+		if *junit != "" {
+			if err := writeJUnit(*junit, newJUnitSuite(podData, violations)); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *junit, err)
+				out.Close()
+				os.Exit(1)
+			}
+		}
+		if len(violations) > 0 {
+			// os.Exit skips the deferred close
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	if *scale != 1 {
+		scalePods(podData, *scale)
+		printScaleLabel(out, *scale)
+	}
+	switch output {
+	case "json":
+		if *kubectlCompat {
+			if err := printJSON(out, newKubectlList(podData, qosResources), *compact); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				out.Close()
+				os.Exit(1)
+			}
+			return
+		}
+		if err := printJSON(out, versionedReport(newReport(podData, qosResources), *outputVersion, collectOpts.podName != ""), *compact); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	case "yaml":
+		if err := printYAML(out, versionedReport(newReport(podData, qosResources), *outputVersion, collectOpts.podName != "")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	case "name":
+		printNames(out, podData)
+		return
+	case "env":
+		printEnv(out, podData, qosResources)
+		return
+	}
+	if goTemplate != nil {
+		if err := printGoTemplate(out, goTemplate, serializable(newReport(podData, qosResources), collectOpts.podName != "")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	if jsonPath != nil {
+		if err := printJSONPath(out, jsonPath, serializable(newReport(podData, qosResources), collectOpts.podName != "")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	if *summary {
+		printSummary(out, summarize(podData), *allContexts)
+		return
+	}
+	if *totalsByOwner {
+		// with --all-contexts or --from-file there is no one cluster to
+		// follow ReplicaSets in, they are shown as they are
+		var owners *ownerResolver
+		if clientset != nil && !*allContexts {
+			owners = newOwnerResolver(clientset, ownerCache)
+		}
+		totals, err := ownerTotals(collectOpts.context(), podData, owners)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		printOwnerTotals(out, totals, quantityStyle, *allContexts)
+		return
+	}
+	if *burstHeadroomFlag {
+		printHeadrooms(out, burstHeadrooms(podData, qosResources), qosResources, quantityStyle, *allContexts)
+		return
+	}
+	tableOpts := tableOptions{
+		showContext:         *allContexts,
+		labelColumns:        splitList(labelColumns),
+		annotationColumns:   splitList(*annotationColumns),
+		showHPA:             *withHPA,
+		resources:           qosResources,
+		showHasLimits:       *showHasLimits,
+		quantityStyle:       quantityStyle,
+		showNodeStatus:      *withNodeStatus,
+		grouped:             *grouped || *groupBy == "pod",
+		compactNamespaces:   *compactNamespaces,
+		showPercentOfNode:   *percentOfNodeFlag,
+		maxRows:             *maxRows,
+		showOS:              *showOS,
+		markdown:            output == "markdown",
+		visual:              *visual && *outputFile == "" && isTerminal(os.Stdout),
+		asciiOnly:           *asciiOnlyFlag,
+		shortClass:          *shortClass,
+		showNodePressure:    *withNodePressure,
+		showTemplateHash:    *showTemplateHash,
+		groupByTemplateHash: *groupBy == "template-hash",
+		pricing:             pricing,
+		showUsage:           *withUsage,
+		showProbes:          *showProbes,
+		showType:            *showType,
+		showPriority:        *showPriority,
+		showRestarts:        *showRestarts,
+		showStatusClass:     *showStatusClass || *onlyMismatches,
+		showClaims:          *showClaims,
+		tsv:                 output == "tsv",
+		noHeaders:           *noHeaders,
+	}
+	if *effective {
+		printEffective(out, podData, tableOpts)
+		return
+	}
+	printTable(out, podData, tableOpts)
+	if *shortClass {
+		fmt.Fprintln(out, shortClassLegend)
+	}
+	if *showType {
+		fmt.Fprintln(out, containerTypeLegend)
+	}
+	if *showSummaryFooter && !*noHeaders && *outputFile == "" && isTerminal(os.Stdout) {
+		printSummaryFooter(out, podData, qosResources)
+	}
+	if pricing != nil {
+		fmt.Fprintln(out)
+		printCostTotals(out, podData, pricing, *allContexts)
+	}
+	if *withQuota {
+		usages, err := collectQuotas(collectOpts.context(), clientset, namespace)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			out.Close()
+			os.Exit(1)
+		}
+		fmt.Fprintln(out)
+		printQuotas(out, usages, quantityStyle)
+	}
+	if *explainEviction {
+		fmt.Fprintln(out)
+		printEvictionExplanations(out, podData, qosResources)
+	}
+	if *showLegend {
+		fmt.Fprintln(out)
+		printLegend(out)
+	}
+}
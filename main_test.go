@@ -0,0 +1,476 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestLabelColumns(t *testing.T) {
+	labelled := newPod("default", "web", burstableContainer("app"))
+	labelled.Labels = map[string]string{"app.kubernetes.io/name": "web", "team": "payments"}
+	unlabelled := newPod("default", "db", guaranteedContainer("pg"))
+
+	opts := tableOptions{labelColumns: splitList("app.kubernetes.io/name, team")}
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(labelled, unlabelled), opts)
+	lines := strings.Split(buf.String(), "\n")
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "NAMESPACE POD NAME CONTAINER CPUl CPUr CLASS NAME TEAM" {
+		t.Errorf("header = %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "default web app 1 250m Burstable web payments" {
+		t.Errorf("labelled row = %q", got)
+	}
+	// the missing labels leave the columns empty
+	if got := strings.TrimRight(lines[2], " "); !strings.HasSuffix(got, "Guaranteed") {
+		t.Errorf("unlabelled row = %q, want it to end at the class", got)
+	}
+}
+
+func TestAnnotationColumns(t *testing.T) {
+	annotated := newPod("default", "web", burstableContainer("app"))
+	annotated.Annotations = map[string]string{"example.com/cost-center": "cc-42"}
+	plain := newPod("default", "db", guaranteedContainer("pg"))
+
+	opts := tableOptions{labelColumns: []string{"team"}, annotationColumns: []string{"example.com/cost-center"}}
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(annotated, plain), opts)
+	lines := strings.Split(buf.String(), "\n")
+	if got := strings.Join(strings.Fields(lines[0]), " "); !strings.HasSuffix(got, " CLASS TEAM COST-CENTER") {
+		t.Errorf("header = %q, want the label then the annotation column", got)
+	}
+	// cc-42 sits under its header, past the empty label
+	if column := strings.Index(lines[0], "COST-CENTER"); strings.Index(lines[1], "cc-42") != column {
+		t.Errorf("annotated row = %q, want cc-42 at column %d", lines[1], column)
+	}
+	if got := strings.TrimRight(lines[2], " "); !strings.HasSuffix(got, "Guaranteed") {
+		t.Errorf("plain row = %q, want both columns blank", got)
+	}
+}
+
+func TestLoadKubeconfigMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nclusters: {not a list\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, path)
+	_, err := loadKubeconfig()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to load kubeconfig: ") {
+		t.Errorf("loadKubeconfig = %v, want the friendly error", err)
+	}
+}
+
+func TestLoadKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	config := "apiVersion: v1\nkind: Config\ncurrent-context: dev\ncontexts:\n- name: dev\n  context: {cluster: dev, namespace: team-a}\nclusters:\n- name: dev\n  cluster: {server: https://dev.example.com}\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, path)
+	clientCfg, err := loadKubeconfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contextNamespace(clientCfg); got != "team-a" {
+		t.Errorf("namespace = %q, want team-a", got)
+	}
+}
+
+func TestEmptyCurrentContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nkind: Config\ncurrent-context: \"\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, path)
+	clientCfg, err := loadKubeconfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contextNamespace(clientCfg); got != "" {
+		t.Errorf("contextNamespace = %q, want none without a current context", got)
+	}
+	if got := contextNamespace(nil); got != "" {
+		t.Errorf("contextNamespace = %q, want none without a kubeconfig", got)
+	}
+	for _, tt := range []struct {
+		flag string
+		all  bool
+		want string
+	}{
+		{"payments", false, "payments"},
+		{"", false, "default"},
+		{"payments", true, ""},
+	} {
+		if got := resolveNamespace(contextNamespace(clientCfg), tt.flag, tt.all); got != tt.want {
+			t.Errorf("resolveNamespace(-n %q, -A %v) = %q, want %q", tt.flag, tt.all, got, tt.want)
+		}
+	}
+
+	// the server comes from the flags, the namespace from -n
+	server := newAPIServer(t, newPod("payments", "api", guaranteedContainer("app")), newPod("default", "web", bestEffortContainer("app")))
+	clientset, err := clusterFlags{server: server.URL}.newClientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podData, err := collect(clientset, resolveNamespace(contextNamespace(clientCfg), "payments", false), collectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(podData), " "); got != "payments/api" {
+		t.Errorf("collected %q, want the pods of payments", got)
+	}
+}
+
+func TestTokenFlags(t *testing.T) {
+	config, err := clusterFlags{server: "https://10.0.0.1:6443", token: "ci-token", certificateAuthority: "/etc/ca.crt"}.restConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://10.0.0.1:6443" || config.BearerToken != "ci-token" || config.TLSClientConfig.CAFile != "/etc/ca.crt" {
+		t.Errorf("config = %s %q %q, want the server, token and ca from the flags", config.Host, config.BearerToken, config.TLSClientConfig.CAFile)
+	}
+	if config, err := (clusterFlags{}).restConfig(); config != nil || err != nil {
+		t.Errorf("restConfig without flags = %v %v, want kubeconfig to be used", config, err)
+	}
+	for _, f := range []clusterFlags{{token: "ci-token"}, {certificateAuthority: "/etc/ca.crt"}} {
+		if _, err := f.restConfig(); err == nil || !strings.Contains(err.Error(), "need --server") {
+			t.Errorf("restConfig(%+v) = %v, want --server to be required", f, err)
+		}
+	}
+}
+
+func TestTokenIsSent(t *testing.T) {
+	var auth atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[]}`)
+	}))
+	defer server.Close()
+	clientset, err := clusterFlags{server: server.URL, token: "ci-token"}.newClientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collect(clientset, "default", collectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := auth.Load(); got != "Bearer ci-token" {
+		t.Errorf("Authorization = %v, want the token", got)
+	}
+}
+
+func TestProtobufByDefault(t *testing.T) {
+	config := &rest.Config{}
+	useProtobuf(config)
+	if config.ContentType != "application/vnd.kubernetes.protobuf" || !strings.HasPrefix(config.AcceptContentTypes, "application/vnd.kubernetes.protobuf,") {
+		t.Errorf("config = %q accepting %q, want protobuf with a json fallback", config.ContentType, config.AcceptContentTypes)
+	}
+
+	var accept atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept.Store(r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[]}`)
+	}))
+	defer server.Close()
+	for jsonProtocol, want := range map[bool]string{false: "application/vnd.kubernetes.protobuf", true: "application/json"} {
+		clientset, err := clusterFlags{server: server.URL, jsonProtocol: jsonProtocol}.newClientset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := collect(clientset, "default", collectOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := accept.Load().(string); !strings.HasPrefix(got, want) {
+			t.Errorf("--json-protocol=%v lists accepting %q, want %s", jsonProtocol, got, want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	f := clusterFlags{server: "https://10.0.0.1:6443", qps: 5, burst: 10}
+	config, err := f.clientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.QPS != 5 || config.Burst != 10 {
+		t.Errorf("config QPS %v Burst %d, want 5 and 10", config.QPS, config.Burst)
+	}
+
+	// the kubeconfig path is throttled the same way
+	path := filepath.Join(t.TempDir(), "config")
+	kubeconfig := "apiVersion: v1\nkind: Config\ncurrent-context: dev\ncontexts:\n- name: dev\n  context: {cluster: dev}\nclusters:\n- name: dev\n  cluster: {server: https://dev.example.com}\n"
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, path)
+	config, err = clusterFlags{qps: 2.5, burst: 3}.clientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://dev.example.com" || config.QPS != 2.5 || config.Burst != 3 {
+		t.Errorf("config %s QPS %v Burst %d, want dev throttled to 2.5 and 3", config.Host, config.QPS, config.Burst)
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	got := parseResources(" cpu,memory,,nvidia.com/gpu")
+	if len(got) != 3 || got[0] != v1.ResourceCPU || got[1] != v1.ResourceMemory || got[2] != "nvidia.com/gpu" {
+		t.Errorf("parseResources = %v", got)
+	}
+	if got := parseResources(""); len(got) != 1 || got[0] != v1.ResourceCPU {
+		t.Errorf("parseResources of nothing = %v, want the default cpu", got)
+	}
+}
+
+func TestResourceColumns(t *testing.T) {
+	gpu := newPod("default", "train", newContainer("trainer", quantities("cpu", "2", "nvidia.com/gpu", "1"), quantities("cpu", "1", "nvidia.com/gpu", "1")))
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(gpu), tableOptions{resources: []v1.ResourceName{"nvidia.com/gpu", v1.ResourceMemory}})
+	lines := strings.Split(buf.String(), "\n")
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "NAMESPACE POD NAME CONTAINER GPUl GPUr MEMl MEMr CLASS" {
+		t.Errorf("header = %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "default train trainer 1 1 <none> <none> Burstable" {
+		t.Errorf("row = %q, want Burstable on gpu and memory", got)
+	}
+}
+
+func TestCollectWarnsWhenTruncated(t *testing.T) {
+	var pods []k8sruntime.Object
+	for i := 0; i < 10; i++ {
+		pods = append(pods, newPod("default", fmt.Sprintf("web-%d", i), bestEffortContainer("app")))
+	}
+	clientset := fake.NewSimpleClientset(pods...)
+	log := captureLog(t)
+
+	podData, err := collect(clientset, "default", collectOptions{maxPods: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 3 {
+		t.Errorf("collected %v, want 3 pods", names(podData))
+	}
+	if want := "warning: output truncated to 3 pods, raise --max-pods to see more\n"; log.String() != want {
+		t.Errorf("log = %q, want %q", log.String(), want)
+	}
+}
+
+func TestHasLimitsColumns(t *testing.T) {
+	zero := newPod("default", "zero", newContainer("app", quantities("cpu", "0", "memory", "0"), nil))
+	absent := newPod("default", "absent", newContainer("app", quantities("memory", "1Gi"), nil))
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(zero, absent), tableOptions{showHasLimits: true})
+	lines := strings.Split(buf.String(), "\n")
+	if got := strings.Fields(lines[0]); strings.Join(got[len(got)-2:], " ") != "HAS-CPU-LIMIT HAS-MEM-LIMIT" {
+		t.Errorf("header = %v", got)
+	}
+	// an explicit 0 counts as set
+	if got := strings.Fields(lines[1]); strings.Join(got[len(got)-2:], " ") != "true true" {
+		t.Errorf("explicit 0 row = %v, want both limits set", got)
+	}
+	if got := strings.Fields(lines[2]); strings.Join(got[len(got)-2:], " ") != "false true" {
+		t.Errorf("absent cpu row = %v, want no cpu limit", got)
+	}
+}
+
+func TestQuantityCellUnsetAndZero(t *testing.T) {
+	c := podqos.NewContainerData(newContainer("app", quantities("cpu", "1"), quantities("cpu", "0")))
+	if got := quantityCell(format.Human, c.Requests, v1.ResourceCPU); got != "0" {
+		t.Errorf("explicit 0 request = %q, want 0", got)
+	}
+	if got := quantityCell(format.Human, c.Requests, v1.ResourceMemory); got != "<none>" {
+		t.Errorf("absent request = %q, want <none>", got)
+	}
+}
+
+func TestTableQuantityStyles(t *testing.T) {
+	web := newPod("default", "web", newContainer("app", quantities("cpu", "1000m", "memory", "1536Mi"), quantities("cpu", "250m", "memory", "1T")))
+	for style, want := range map[format.Style]string{
+		format.Human:      "1 250m 1.5Gi 931.3Gi",
+		format.Raw:        "1 250m 1536Mi 1T",
+		format.Scientific: "1e+00 2.5e-01 1.610612736e+09 1e+12",
+	} {
+		var buf bytes.Buffer
+		printTable(&buf, toPodData(web), tableOptions{resources: cpuMemory, quantityStyle: style})
+		if got := strings.Join(strings.Fields(strings.Split(buf.String(), "\n")[1])[3:7], " "); got != want {
+			t.Errorf("%s quantities = %q, want %q", style, got, want)
+		}
+	}
+}
+
+func TestZeroContainerPod(t *testing.T) {
+	podData := toPodData(newPod("default", "empty"), newPod("default", "web", guaranteedContainer("app")))
+
+	for name, opts := range map[string]tableOptions{
+		"flat":    {},
+		"grouped": {grouped: true},
+		"tsv":     {tsv: true},
+	} {
+		var buf bytes.Buffer
+		printTable(&buf, podData, opts)
+		if !strings.Contains(buf.String(), noContainers) || !strings.Contains(buf.String(), "BestEffort") {
+			t.Errorf("%s table = %q, want a BestEffort %s row", name, buf.String(), noContainers)
+		}
+	}
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "default empty <no containers> <none> <none> BestEffort" {
+		t.Errorf("row = %q", got)
+	}
+	if len(podData[0].Containers) != 0 {
+		t.Errorf("the placeholder was added to the pod data, %v", podData[0].Containers)
+	}
+
+	// and it is counted in the summaries
+	if summaries := summarize(podData); len(summaries) != 1 || summaries[0].Pods != 2 {
+		t.Errorf("summarize = %+v, want 2 pods", summaries)
+	}
+	buf.Reset()
+	printSummaryFooter(&buf, podData, cpuMemory)
+	if want := "# 2 pods: 1 Guaranteed, 0 Burstable, 1 BestEffort\n"; buf.String() != want {
+		t.Errorf("footer = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintGrouped(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{grouped: true})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	want := []string{
+		"  CONTAINER  CPUl  CPUr  CLASS",
+		"default/web",
+		"  app        1     250m  Burstable",
+		"  proxy      <none>  <none>  BestEffort",
+		"default/db",
+		"  pg         1     1     Guaranteed",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("grouped = %q", lines)
+	}
+	for i, line := range lines {
+		if strings.Join(strings.Fields(line), " ") != strings.Join(strings.Fields(want[i]), " ") {
+			t.Errorf("line %d = %q, want %q", i, line, want[i])
+		}
+		// the pod line is printed once, every container is indented beneath it
+		if indented := strings.HasPrefix(line, "  "); indented != strings.HasPrefix(want[i], "  ") {
+			t.Errorf("line %d = %q, indented %v", i, line, indented)
+		}
+	}
+	if strings.Count(buf.String(), "default/web") != 1 {
+		t.Errorf("the web pod is printed %d times, want once", strings.Count(buf.String(), "default/web"))
+	}
+}
+
+func TestExecCredentialPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub plugin is a shell script")
+	}
+	var auth atomic.Value
+	// client-go only authenticates over tls
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","items":[]}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "credential-plugin")
+	script := "#!/bin/sh\necho '{\"apiVersion\":\"client.authentication.k8s.io/v1\",\"kind\":\"ExecCredential\",\"status\":{\"token\":\"stub-token\"}}'\n"
+	if err := os.WriteFile(plugin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: eks
+contexts:
+- name: eks
+  context: {cluster: eks, user: eks}
+clusters:
+- name: eks
+  cluster: {server: %q, insecure-skip-tls-verify: true}
+users:
+- name: eks
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: %q
+      interactiveMode: Never
+`, server.URL, plugin)
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, path)
+
+	clientset, err := clusterFlags{}.newClientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := podqos.CollectPodData(clientset, "default"); err != nil {
+		t.Fatal(err)
+	}
+	if got := auth.Load(); got != "Bearer stub-token" {
+		t.Errorf("Authorization = %q, want the token from the plugin", got)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy")),
+		newPod("default", "db", guaranteedContainer("pg"), bestEffortContainer("exporter")),
+		newPod("default", "cache", guaranteedContainer("redis")))
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{maxRows: 3})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	// the header, three rows and the footer
+	if len(lines) != 5 {
+		t.Fatalf("table = %q, want 3 rows", lines)
+	}
+	if got := strings.Fields(lines[3]); got[1] != "db" || got[2] != "pg" {
+		t.Errorf("last row = %v, want db/pg", got)
+	}
+	if lines[4] != "... and 2 more, use -o json for all" {
+		t.Errorf("footer = %q", lines[4])
+	}
+
+	// no footer when everything fits
+	buf.Reset()
+	printTable(&buf, podData, tableOptions{maxRows: 5})
+	if strings.Contains(buf.String(), "more") {
+		t.Errorf("table of exactly max rows = %q, want no footer", buf.String())
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// stdinPath reads the manifests of --from-file from stdin
+const stdinPath = "-"
+
+// readManifests reads the manifests of --from-file, from stdin for "-"
+func readManifests(path string, stdin io.Reader) ([]podqos.PodData, error) {
+	if path == stdinPath {
+		podData, err := decodeManifests(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifests from stdin: %v", err)
+		}
+		return podData, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	podData, err := decodeManifests(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests from %s: %v", path, err)
+	}
+	return podData, nil
+}
+
+// decodeManifests decodes a stream of yaml documents or json objects, e.g.
+// the output of kubectl get -o yaml, into the pod data of every pod and the
+// pod template of every workload in it. Nothing is looked up in a cluster
+func decodeManifests(r io.Reader) ([]podqos.PodData, error) {
+	var podData []podqos.PodData
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return podData, nil
+			}
+			return nil, err
+		}
+		// empty documents, e.g. a leading ---, decode to null
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		objects, err := decodeObject(raw)
+		if err != nil {
+			return nil, err
+		}
+		podData = append(podData, objects...)
+	}
+}
+
+// decodeObject decodes a single manifest by its kind, a List is decoded item
+// by item and a workload is anything podqos.NewWorkload makes
+func decodeObject(raw []byte) ([]podqos.PodData, error) {
+	var meta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, err
+	}
+	switch meta.Kind {
+	case "Pod":
+		var pod v1.Pod
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return nil, err
+		}
+		return []podqos.PodData{podqos.NewPodData(pod)}, nil
+	case "List":
+		var list v1.List
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		var podData []podqos.PodData
+		for _, item := range list.Items {
+			objects, err := decodeObject(item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			podData = append(podData, objects...)
+		}
+		return podData, nil
+	}
+	obj := podqos.NewWorkload(meta.Kind)
+	if obj == nil {
+		return nil, fmt.Errorf("unsupported kind %q, use Pod, Deployment, StatefulSet, DaemonSet, Job or a List of them", meta.Kind)
+	}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, err
+	}
+	podData, _ := podqos.WorkloadTemplate(obj)
+	return []podqos.PodData{podData}, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const manifests = `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  containers:
+  - name: app
+    resources:
+      limits: {cpu: "1", memory: 1Gi}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: shop
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests: {cpu: 250m}
+`
+
+func TestManifestsFromStdin(t *testing.T) {
+	podData, err := readManifests(stdinPath, bytes.NewReader([]byte(manifests)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(podData), " "); got != "shop/web shop/deployment/api" {
+		t.Fatalf("read %q, want the pod and the deployment", got)
+	}
+	for i, want := range []string{"Guaranteed", "Burstable"} {
+		if got := string(podData[i].QosClass(cpuMemory)); got != want {
+			t.Errorf("%s class = %s, want %s", podData[i].PodName, got, want)
+		}
+	}
+}
+
+func TestManifestsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pods.yaml")
+	if err := os.WriteFile(path, []byte(manifests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// stdin is only read for -
+	podData, err := readManifests(path, strings.NewReader("kind: Secret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 2 {
+		t.Errorf("read %q, want the file", names(podData))
+	}
+	if _, err := readManifests(stdinPath, strings.NewReader("apiVersion: v1\nkind: Secret\n")); err == nil || !strings.Contains(err.Error(), `unsupported kind "Secret"`) {
+		t.Errorf("readManifests of a Secret = %v, want unsupported kind", err)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// markdownRow renders the cells as a GitHub flavored markdown table row,
+// pipes inside a value are escaped so they don't split the cell
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.Replace(cell, "|", `\|`, -1)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// printMarkdown writes the flat table as markdown, for pasting into issues
+// and runbooks
+func printMarkdown(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	header := append(podHeader(opts), containerHeader(opts)...)
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	fmt.Fprintln(w, markdownRow(header))
+	fmt.Fprintln(w, "|"+strings.Join(separator, "|")+"|")
+	for _, v := range podData {
+		for _, c := range v.Containers {
+			fmt.Fprintln(w, markdownRow(append(podCells(v, opts), containerCells(v, c, opts)...)))
+		}
+	}
+}
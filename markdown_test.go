@@ -0,0 +1,40 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintMarkdown(t *testing.T) {
+	web := newPod("default", "web", burstableContainer("app"))
+	web.Labels = map[string]string{"team": "a|b"}
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(web), tableOptions{markdown: true, labelColumns: []string{"team"}})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("markdown = %q, want a header, separator and row", lines)
+	}
+	if lines[0] != "| NAMESPACE | POD NAME | CONTAINER | CPUl | CPUr | CLASS | TEAM |" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if lines[1] != "|---|---|---|---|---|---|---|" {
+		t.Errorf("separator = %q, want one --- per column", lines[1])
+	}
+	if lines[2] != `| default | web | app | 1 | 250m | Burstable | a\|b |` {
+		t.Errorf("row = %q, want the pipe in the label escaped", lines[2])
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// readNamespaceFile reads one namespace per line, blank lines and lines
+// starting with # are skipped and duplicates only count once
+func readNamespaceFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var namespaces []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ns := strings.TrimSpace(scanner.Text())
+		if ns == "" || strings.HasPrefix(ns, "#") || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("no namespaces found in %s", path)
+	}
+	return namespaces, nil
+}
+
+// systemNamespaces are left out by --exclude-system
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// excludeSelector is the field selector leaving out the namespaces
+func excludeSelector(excluded []string) string {
+	var selectors []fields.Selector
+	for _, ns := range excluded {
+		selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", ns))
+	}
+	return fields.AndSelectors(selectors...).String()
+}
+
+// includeNamespaces is the included namespaces that aren't excluded too,
+// exclude wins when a namespace is in both
+func includeNamespaces(included, excluded []string) []string {
+	drop := map[string]bool{}
+	for _, ns := range excluded {
+		drop[ns] = true
+	}
+	var namespaces []string
+	for _, ns := range included {
+		if !drop[ns] {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// selectNamespaces lists the names of the namespaces matching the label
+// selector, e.g. team=payments, in name order
+func selectNamespaces(ctx context.Context, clientset kubernetes.Interface, selector string) ([]string, error) {
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, ns := range list.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// collectNamespaces lists every namespace concurrently, the result keeps
+// the order the namespaces were given in. When interrupted it returns what
+// the namespaces got through along with the error
+func collectNamespaces(clientset kubernetes.Interface, namespaces []string, opts collectOptions) ([]podqos.PodData, error) {
+	results := make([][]podqos.PodData, len(namespaces))
+	errs := make([]error, len(namespaces))
+	// a node running pods of several namespaces is only fetched once
+	if opts.nodes == nil {
+		opts.nodes = newNodeLookup(clientset)
+	}
+	var wg sync.WaitGroup
+	for i, ns := range namespaces {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			results[i], errs[i] = collect(clientset, ns, opts)
+			opts.progress.step("scanned", "namespace", ns, len(namespaces))
+		}(i, ns)
+	}
+	wg.Wait()
+	opts.progress.finish()
+
+	var podData []podqos.PodData
+	if opts.interrupted() {
+		for i := range namespaces {
+			podData = append(podData, results[i]...)
+		}
+		return podData, opts.ctx.Err()
+	}
+	for i := range namespaces {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("namespace %s: %v", namespaces[i], errs[i])
+		}
+		podData = append(podData, results[i]...)
+	}
+	return podData, nil
+}
+
+// checkNamespace makes sure the namespace exists, listing pods in a
+// mistyped namespace just comes back empty with no hint why
+func checkNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("namespace %q not found", namespace)
+	case apierrors.IsForbidden(err):
+		return fmt.Errorf("%v, use --skip-namespace-check when you can list pods but not get namespaces", err)
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// listedNamespaces records the namespaces pods are listed in
+func listedNamespaces(clientset *fake.Clientset) func() []string {
+	var mu sync.Mutex
+	var listed []string
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		listed = append(listed, action.GetNamespace())
+		mu.Unlock()
+		return false, nil, nil
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sorted := append([]string(nil), listed...)
+		sort.Strings(sorted)
+		return sorted
+	}
+}
+
+func TestNamespaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "namespaces")
+	content := "payments\n  search \n\n# the team's scratch namespace\nbatch\npayments\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	namespaces, err := readNamespaceFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(namespaces, " ") != "payments search batch" {
+		t.Fatalf("readNamespaceFile = %q, want three namespaces in file order", namespaces)
+	}
+
+	clientset := fake.NewSimpleClientset(
+		newPod("payments", "api", bestEffortContainer("app")),
+		newPod("search", "indexer", bestEffortContainer("app")),
+		newPod("batch", "job", bestEffortContainer("app")),
+		newPod("other", "web", bestEffortContainer("app")))
+	listed := listedNamespaces(clientset)
+	podData, err := collectNamespaces(clientset, namespaces, collectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(listed(), " "); got != "batch payments search" {
+		t.Errorf("listed %q, want each namespace of the file once", got)
+	}
+	if got := strings.Join(names(podData), " "); got != "payments/api search/indexer batch/job" {
+		t.Errorf("collected %q", got)
+	}
+}
+
+func TestEmptyNamespaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "namespaces")
+	if err := os.WriteFile(path, []byte("\n# nothing yet\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readNamespaceFile(path); err == nil {
+		t.Error("readNamespaceFile of an empty file = nil error")
+	}
+	if _, err := readNamespaceFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("readNamespaceFile of a missing file = nil error")
+	}
+}
+
+func TestCheckNamespace(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+		newPod("payments", "api", bestEffortContainer("app")))
+	podData, err := collect(clientset, "payments", collectOptions{checkNamespace: true})
+	if err != nil || len(podData) != 1 {
+		t.Errorf("collect in an existing namespace = %d pods, %v", len(podData), err)
+	}
+	if _, err := collect(clientset, "nonexistent", collectOptions{checkNamespace: true}); err == nil || err.Error() != `namespace "nonexistent" not found` {
+		t.Errorf("collect in a missing namespace = %v, want not found", err)
+	}
+	// skipped, or with -A, the namespace isn't looked up
+	if _, err := collect(clientset, "nonexistent", collectOptions{}); err != nil {
+		t.Errorf("collect with --skip-namespace-check = %v", err)
+	}
+	if _, err := collect(clientset, "", collectOptions{checkNamespace: true}); err != nil {
+		t.Errorf("collect in all namespaces = %v", err)
+	}
+}
+
+func TestCheckNamespaceForbidden(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(v1.Resource("namespaces"), "payments", nil)
+	})
+	if err := checkNamespace(context.Background(), clientset, "payments"); err == nil || !strings.Contains(err.Error(), "--skip-namespace-check") {
+		t.Errorf("checkNamespace = %v, want a hint to skip the check", err)
+	}
+}
+
+func TestSelectNamespaces(t *testing.T) {
+	namespace := func(name, team string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": team}}}
+	}
+	clientset := fake.NewSimpleClientset(
+		namespace("payments-api", "payments"),
+		namespace("payments-batch", "payments"),
+		namespace("search", "search"),
+		newPod("payments-api", "api", guaranteedContainer("app")),
+		newPod("payments-batch", "job", bestEffortContainer("app")),
+		newPod("search", "indexer", bestEffortContainer("app")))
+	namespaces, err := selectNamespaces(context.Background(), clientset, "team=payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(namespaces, " ") != "payments-api payments-batch" {
+		t.Fatalf("selectNamespaces = %q, want the payments namespaces in name order", namespaces)
+	}
+	podData, err := collectNamespaces(clientset, namespaces, collectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(podData), " "); got != "payments-api/api payments-batch/job" {
+		t.Errorf("collected %q", got)
+	}
+
+	none, err := selectNamespaces(context.Background(), clientset, "team=nobody")
+	if err != nil || len(none) != 0 {
+		t.Errorf("selectNamespaces with no match = %q %v, want none", none, err)
+	}
+	if podData, err := collectNamespaces(clientset, none, collectOptions{}); err != nil || len(podData) != 0 {
+		t.Errorf("collectNamespaces of none = %d pods %v", len(podData), err)
+	}
+}
+
+// honorFieldSelectors has the clientset's pod lists apply the field selector
+// on the namespace and name like the api server does, the fake ignores it
+func honorFieldSelectors(clientset *fake.Clientset) {
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.ListAction).GetListRestrictions().Fields
+		obj, err := clientset.Tracker().List(v1.SchemeGroupVersion.WithResource("pods"), v1.SchemeGroupVersion.WithKind("Pod"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		list := obj.(*v1.PodList)
+		var kept []v1.Pod
+		for _, pod := range list.Items {
+			if selector.Matches(fields.Set{"metadata.namespace": pod.Namespace, "metadata.name": pod.Name}) {
+				kept = append(kept, pod)
+			}
+		}
+		list.Items = kept
+		return true, list, nil
+	})
+}
+
+// clusterPods is a pod in each system namespace and two of the user's
+func clusterPods() *fake.Clientset {
+	var objects []runtime.Object
+	for _, ns := range []string{"kube-system", "kube-public", "kube-node-lease", "shop", "blog"} {
+		objects = append(objects, newPod(ns, "web", bestEffortContainer("app")))
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	honorFieldSelectors(clientset)
+	return clientset
+}
+
+func TestExcludeSystemNamespaces(t *testing.T) {
+	clientset := clusterPods()
+	podData, err := collect(clientset, "", collectOptions{fieldSelector: excludeSelector(systemNamespaces)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(podData), " "); got != "blog/web shop/web" {
+		t.Errorf("--exclude-system collected %q, want only the user namespaces", got)
+	}
+	podData, err = collect(clientset, "", collectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 5 {
+		t.Errorf("without --exclude-system collected %q, want every namespace", names(podData))
+	}
+	// --exclude-namespaces replaces the system namespaces
+	podData, err = collect(clientset, "", collectOptions{fieldSelector: excludeSelector([]string{"blog"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(podData), " "); strings.Contains(got, "blog") || !strings.Contains(got, "kube-system/web") {
+		t.Errorf("--exclude-namespaces blog collected %q", got)
+	}
+}
+
+func TestIncludeExcludeNamespaces(t *testing.T) {
+	for _, tt := range []struct {
+		included, excluded string
+		want               string
+	}{
+		{"shop,blog", "", "shop blog"},
+		{"shop,blog", "blog", "shop"},
+		{"shop", "shop", ""},
+	} {
+		if got := strings.Join(includeNamespaces(splitList(tt.included), splitList(tt.excluded)), " "); got != tt.want {
+			t.Errorf("include %q exclude %q = %q, want %q", tt.included, tt.excluded, got, tt.want)
+		}
+	}
+	if got := excludeSelector([]string{"kube-system", "blog"}); got != "metadata.namespace!=kube-system,metadata.namespace!=blog" {
+		t.Errorf("excludeSelector = %q", got)
+	}
+
+	// only the included namespaces are listed, not the whole cluster
+	clientset := clusterPods()
+	listed := listedNamespaces(clientset)
+	podData, err := collectNamespaces(clientset, includeNamespaces([]string{"shop", "blog", "kube-system"}, []string{"kube-system"}), collectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(listed(), " "); got != "blog shop" {
+		t.Errorf("listed %q, want just blog and shop", got)
+	}
+	if got := strings.Join(names(podData), " "); got != "shop/web blog/web" {
+		t.Errorf("collected %q", got)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeLookup gets nodes by name, every node is only fetched once per run
+// however many pods run on it
+type nodeLookup struct {
+	clientset kubernetes.Interface
+	cache     *objectCache
+}
+
+func newNodeLookup(clientset kubernetes.Interface) *nodeLookup {
+	return &nodeLookup{clientset: clientset, cache: newObjectCache()}
+}
+
+// get returns the node, nil for pods that aren't scheduled yet
+func (n *nodeLookup) get(ctx context.Context, name string) (*v1.Node, error) {
+	if name == "" {
+		return nil, nil
+	}
+	obj, err := n.cache.get("", "Node", name, func() (interface{}, error) {
+		return n.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*v1.Node), nil
+}
+
+// nodeStatus summarizes whether new pods can land on the node, e.g.
+// "Cordoned,NoSchedule" or "Schedulable"
+func nodeStatus(node *v1.Node) string {
+	var status []string
+	if node.Spec.Unschedulable {
+		status = append(status, "Cordoned")
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule {
+			status = append(status, "NoSchedule")
+			break
+		}
+	}
+	if len(status) == 0 {
+		return "Schedulable"
+	}
+	return strings.Join(status, ",")
+}
+
+// annotateNodeStatus sets the NodeStatus of every scheduled pod, draining
+// BestEffort pods off cordoned or tainted nodes is a common chore so it
+// helps to see both together
+func annotateNodeStatus(ctx context.Context, nodes *nodeLookup, podData []podqos.PodData) error {
+	for i := range podData {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		node, err := nodes.get(ctx, podData[i].NodeName)
+		if err != nil {
+			return err
+		}
+		if node != nil {
+			podData[i].NodeStatus = nodeStatus(node)
+		}
+	}
+	return nil
+}
+
+// annotateNodeAllocatable sets the NodeAllocatable of every scheduled pod
+func annotateNodeAllocatable(ctx context.Context, nodes *nodeLookup, podData []podqos.PodData) error {
+	for i := range podData {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		node, err := nodes.get(ctx, podData[i].NodeName)
+		if err != nil {
+			return err
+		}
+		if node != nil {
+			podData[i].NodeAllocatable = podqos.ResourceData(node.Status.Allocatable)
+		}
+	}
+	return nil
+}
+
+// podOS is the operating system the pod targets through its node selector,
+// otherwise that of the node it landed on, and linux when neither says
+func podOS(pod *v1.Pod, node *v1.Node) string {
+	if pod != nil && pod.Spec.NodeSelector[v1.LabelOSStable] != "" {
+		return pod.Spec.NodeSelector[v1.LabelOSStable]
+	}
+	if node != nil && node.Labels[v1.LabelOSStable] != "" {
+		return node.Labels[v1.LabelOSStable]
+	}
+	return "linux"
+}
+
+// annotateOS sets the OS of every pod, windows containers handle cpu and
+// memory limits differently so it matters when reading their class. The
+// node is only looked up when the pod doesn't select one itself
+func annotateOS(ctx context.Context, nodes *nodeLookup, podData []podqos.PodData) error {
+	for i := range podData {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var node *v1.Node
+		if pod := podData[i].Pod; pod == nil || pod.Spec.NodeSelector[v1.LabelOSStable] == "" {
+			var err error
+			if node, err = nodes.get(ctx, podData[i].NodeName); err != nil {
+				return err
+			}
+		}
+		podData[i].OS = podOS(podData[i].Pod, node)
+	}
+	return nil
+}
+
+// pressureConditions are the node conditions kubelet evicts pods under
+var pressureConditions = []v1.NodeConditionType{v1.NodeMemoryPressure, v1.NodeDiskPressure, v1.NodePIDPressure}
+
+// nodePressure lists the pressure conditions that are currently true
+func nodePressure(node *v1.Node) []string {
+	var pressure []string
+	for _, t := range pressureConditions {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == t && condition.Status == v1.ConditionTrue {
+				pressure = append(pressure, string(t))
+			}
+		}
+	}
+	return pressure
+}
+
+// annotateNodePressure sets the NodePressure of every scheduled pod
+func annotateNodePressure(ctx context.Context, nodes *nodeLookup, podData []podqos.PodData) error {
+	for i := range podData {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		node, err := nodes.get(ctx, podData[i].NodeName)
+		if err != nil {
+			return err
+		}
+		if node != nil {
+			podData[i].NodePressure = nodePressure(node)
+		}
+	}
+	return nil
+}
+
+// pressureCell shows the node's pressure conditions, and marks BestEffort
+// containers on such a node since kubelet evicts them first
+func pressureCell(pressure []string, class podqos.PodQosPolicy) string {
+	if len(pressure) == 0 {
+		return "<none>"
+	}
+	cell := strings.Join(pressure, ",")
+	if class == podqos.BestEffort {
+		cell += " (AT RISK)"
+	}
+	return cell
+}
+
+// percentOfNode is the request as a share of the node's allocatable, n/a
+// for pods that aren't scheduled yet
+func percentOfNode(request *resource.Quantity, allocatable podqos.ResourceData, name v1.ResourceName) string {
+	if !allocatable.Has(name) || allocatable.Get(name).MilliValue() == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", float64(request.MilliValue())*100/float64(allocatable.Get(name).MilliValue()))
+}
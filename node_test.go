@@ -0,0 +1,181 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		node v1.Node
+		want string
+	}{
+		{"schedulable", v1.Node{}, "Schedulable"},
+		{"cordoned", v1.Node{Spec: v1.NodeSpec{Unschedulable: true}}, "Cordoned"},
+		{"tainted", v1.Node{Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}}}}, "NoSchedule"},
+		{"prefer no schedule", v1.Node{Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectPreferNoSchedule}}}}, "Schedulable"},
+		{"cordoned and tainted", v1.Node{Spec: v1.NodeSpec{Unschedulable: true, Taints: []v1.Taint{{Effect: v1.TaintEffectNoSchedule}}}}, "Cordoned,NoSchedule"},
+	}
+	for _, tt := range tests {
+		if got := nodeStatus(&tt.node); got != tt.want {
+			t.Errorf("%s: nodeStatus = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNodeLookupsAreSharedAcrossNamespaces(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: v1.NodeSpec{Unschedulable: true}}
+	clientset := fake.NewSimpleClientset(node,
+		newPod("a", "web", bestEffortContainer("app")),
+		newPod("b", "web", bestEffortContainer("app")),
+		newPod("c", "web", bestEffortContainer("app")))
+	gets := countGets(clientset, "nodes")
+
+	podData, err := collectNamespaces(clientset, []string{"a", "b", "c"}, collectOptions{withNodeStatus: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 3 {
+		t.Fatalf("collected %v, want a pod per namespace", names(podData))
+	}
+	for _, p := range podData {
+		if p.NodeStatus != "Cordoned" {
+			t.Errorf("%s/%s NodeStatus = %q, want Cordoned", p.NameSpace, p.PodName, p.NodeStatus)
+		}
+	}
+	if *gets != 1 {
+		t.Errorf("the node was fetched %d times, want once", *gets)
+	}
+}
+
+func TestNodeLookupsStopWhenCancelled(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	gets := countGets(clientset, "nodes")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	podData := toPodData(newPod("default", "web", bestEffortContainer("app")))
+	if err := annotateNodeStatus(ctx, newNodeLookup(clientset), podData); err != context.Canceled {
+		t.Errorf("annotateNodeStatus after cancel = %v, want %v", err, context.Canceled)
+	}
+	if *gets != 0 || podData[0].NodeStatus != "" {
+		t.Errorf("%d node gets and NodeStatus %q after cancel, want none", *gets, podData[0].NodeStatus)
+	}
+}
+
+func TestPercentOfNode(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     v1.NodeStatus{Allocatable: quantities("cpu", "4", "memory", "16Gi")},
+	}
+	pending := newPod("default", "pending", burstableContainer("app"))
+	pending.Spec.NodeName = ""
+	clientset := fake.NewSimpleClientset(node, newPod("default", "web", burstableContainer("app")), pending)
+
+	podData, err := collect(clientset, "default", collectOptions{withNodeAllocatable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printTable(&buf, podData, tableOptions{showPercentOfNode: true})
+	rows := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		fields := strings.Fields(line)
+		rows[fields[1]] = strings.Join(fields[len(fields)-2:], " ")
+	}
+	// 250m of 4 cpus and 128Mi of 16Gi
+	if rows["web"] != "6.2% 0.8%" {
+		t.Errorf("web = %q, want 6.2%% 0.8%%", rows["web"])
+	}
+	if rows["pending"] != "n/a n/a" {
+		t.Errorf("unscheduled pod = %q, want n/a", rows["pending"])
+	}
+}
+
+func TestAnnotateOS(t *testing.T) {
+	windowsNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "win-1", Labels: map[string]string{v1.LabelOSStable: "windows"}}}
+	selects := newPod("default", "iis", bestEffortContainer("iis"))
+	selects.Spec.NodeName = ""
+	selects.Spec.NodeSelector = map[string]string{v1.LabelOSStable: "windows"}
+	landed := newPod("default", "agent", bestEffortContainer("agent"))
+	landed.Spec.NodeName = "win-1"
+	unscheduled := newPod("default", "pending", bestEffortContainer("app"))
+	unscheduled.Spec.NodeName = ""
+	clientset := fake.NewSimpleClientset(windowsNode)
+	gets := countGets(clientset, "nodes")
+
+	podData := toPodData(selects, landed, unscheduled)
+	if err := annotateOS(context.Background(), newNodeLookup(clientset), podData); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"windows", "windows", "linux"} {
+		if podData[i].OS != want {
+			t.Errorf("%s OS = %q, want %q", podData[i].PodName, podData[i].OS, want)
+		}
+	}
+	// the pod selecting windows doesn't need its node
+	if *gets != 1 {
+		t.Errorf("%d node gets, want only the agent's", *gets)
+	}
+
+	var buf bytes.Buffer
+	printFlat(&buf, podData, tableOptions{showOS: true})
+	if fields := strings.Fields(strings.Split(buf.String(), "\n")[1]); fields[len(fields)-1] != "windows" {
+		t.Errorf("row = %v, want the OS column last", fields)
+	}
+}
+
+func TestNodePressure(t *testing.T) {
+	pressured := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionTrue},
+		{Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue},
+		{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse},
+	}}}
+	clientset := fake.NewSimpleClientset(pressured)
+	gets := countGets(clientset, "nodes")
+	batch := newPod("default", "batch", bestEffortContainer("job"))
+	pending := newPod("default", "pending", bestEffortContainer("job"))
+	pending.Spec.NodeName = ""
+	podData := toPodData(batch, newPod("default", "db", guaranteedContainer("pg")), pending)
+
+	if err := annotateNodePressure(context.Background(), newNodeLookup(clientset), podData); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"MemoryPressure", "MemoryPressure", ""} {
+		if got := strings.Join(podData[i].NodePressure, ","); got != want {
+			t.Errorf("%s NodePressure = %q, want %q", podData[i].PodName, got, want)
+		}
+	}
+	if *gets != 1 {
+		t.Errorf("the node was fetched %d times, want once", *gets)
+	}
+	if got := pressureCell(podData[0].NodePressure, podData[0].Containers[0].QosClass(cpuMemory)); got != "MemoryPressure (AT RISK)" {
+		t.Errorf("BestEffort on a node under pressure = %q, want it marked at risk", got)
+	}
+	if got := pressureCell(podData[1].NodePressure, podData[1].Containers[0].QosClass(cpuMemory)); got != "MemoryPressure" {
+		t.Errorf("Guaranteed on a node under pressure = %q", got)
+	}
+	if got := pressureCell(nil, podqos.BestEffort); got != "<none>" {
+		t.Errorf("no pressure = %q, want <none>", got)
+	}
+}
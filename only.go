@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerPredicate decides whether a container row is kept, the class is
+// computed from the resources the table uses
+type containerPredicate func(c podqos.ContainerData, resources []v1.ResourceName) bool
+
+// classPredicate keeps containers of the class
+func classPredicate(class podqos.PodQosPolicy) containerPredicate {
+	return func(c podqos.ContainerData, resources []v1.ResourceName) bool {
+		return c.QosClass(resources) == class
+	}
+}
+
+// missingPredicate keeps containers that don't set the resource
+func missingPredicate(limits bool, name v1.ResourceName) containerPredicate {
+	return func(c podqos.ContainerData, resources []v1.ResourceName) bool {
+		if limits {
+			return !c.Limits.Has(name)
+		}
+		return !c.Requests.Has(name)
+	}
+}
+
+// onlyPredicates is the vocabulary of --only
+var onlyPredicates = map[string]containerPredicate{
+	"besteffort":          classPredicate(podqos.BestEffort),
+	"burstable":           classPredicate(podqos.Burstable),
+	"guaranteed":          classPredicate(podqos.Guaranteed),
+	"missing-cpu-limit":   missingPredicate(true, v1.ResourceCPU),
+	"missing-mem-limit":   missingPredicate(true, v1.ResourceMemory),
+	"missing-cpu-request": missingPredicate(false, v1.ResourceCPU),
+	"missing-mem-request": missingPredicate(false, v1.ResourceMemory),
+}
+
+// onlyKeys lists the --only vocabulary in name order
+func onlyKeys() []string {
+	var keys []string
+	for key := range onlyPredicates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseOnly parses a comma separated --only value, e.g.
+// besteffort,missing-mem-limit
+func parseOnly(value string) ([]containerPredicate, error) {
+	var predicates []containerPredicate
+	for _, key := range splitList(value) {
+		predicate, ok := onlyPredicates[strings.ToLower(key)]
+		if !ok {
+			return nil, fmt.Errorf("unknown --only %q, use any of: %s", key, strings.Join(onlyKeys(), ", "))
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// filterContainers keeps the containers matching every predicate, pods
+// left without any are dropped
+func filterContainers(podData []podqos.PodData, predicates []containerPredicate, resources []v1.ResourceName) []podqos.PodData {
+	var kept []podqos.PodData
+	for _, p := range podData {
+		var containers []podqos.ContainerData
+		for _, c := range p.Containers {
+			if matchesAll(c, predicates, resources) {
+				containers = append(containers, c)
+			}
+		}
+		if len(containers) > 0 {
+			p.Containers = containers
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// matchesAll reports whether the container matches every predicate
+func matchesAll(c podqos.ContainerData, predicates []containerPredicate, resources []v1.ResourceName) bool {
+	for _, predicate := range predicates {
+		if !predicate(c, resources) {
+			return false
+		}
+	}
+	return true
+}
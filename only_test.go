@@ -0,0 +1,59 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// containerNames is pod/container for every container left
+func containerNames(podData []podqos.PodData) string {
+	var names []string
+	for _, p := range podData {
+		for _, c := range p.Containers {
+			names = append(names, p.PodName+"/"+c.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func TestOnlyCombinesPredicates(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web",
+			newContainer("app", quantities("cpu", "1"), quantities("cpu", "250m", "memory", "128Mi")),
+			newContainer("proxy", quantities("cpu", "1", "memory", "256Mi"), quantities("cpu", "100m"))),
+		newPod("default", "batch", bestEffortContainer("job")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	for value, want := range map[string]string{
+		"burstable":                      "web/app web/proxy",
+		"missing-mem-limit":              "web/app batch/job",
+		"burstable,missing-mem-limit":    "web/app",
+		"BestEffort,missing-cpu-request": "batch/job",
+		"guaranteed,missing-cpu-limit":   "",
+	} {
+		predicates, err := parseOnly(value)
+		if err != nil {
+			t.Fatalf("parseOnly(%q) = %v", value, err)
+		}
+		if got := containerNames(filterContainers(podData, predicates, cpuMemory)); got != want {
+			t.Errorf("--only %s kept %q, want %q", value, got, want)
+		}
+	}
+	if _, err := parseOnly("besteffort,no-probes"); err == nil || !strings.Contains(err.Error(), "missing-mem-limit") {
+		t.Errorf("parseOnly of an unknown key = %v, want the vocabulary listed", err)
+	}
+}
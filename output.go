@@ -0,0 +1,163 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// outputFormats are the values accepted by -o, empty is the table
+var outputFormats = []string{"json", "yaml", "name", "env", "markdown", "tsv", "jsonpath=<template>", "go-template=<template>"}
+
+// jsonPathPrefix starts a -o jsonpath=<template> value
+const jsonPathPrefix = "jsonpath="
+
+// validOutput reports whether -o has a supported value
+func validOutput(output string) bool {
+	if output == "" || strings.HasPrefix(output, jsonPathPrefix) || strings.HasPrefix(output, goTemplatePrefix) {
+		return true
+	}
+	for _, f := range outputFormats {
+		if f == output {
+			return true
+		}
+	}
+	return false
+}
+
+// printJSON writes the report, or a single PodReport, as indented json or
+// on a single line when compact
+func printJSON(w io.Writer, v interface{}, compact bool) error {
+	enc := json.NewEncoder(w)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// parseJSONPath parses the template of -o jsonpath=, e.g. '{.pods[*].name}'
+func parseJSONPath(template string) (*jsonpath.JSONPath, error) {
+	j := jsonpath.New("podqos")
+	if err := j.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath template %q: %v", template, err)
+	}
+	return j, nil
+}
+
+// printJSONPath evaluates the template over the json form of v, so the
+// field names are the same as in -o json
+func printJSONPath(w io.Writer, j *jsonpath.JSONPath, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if err := j.Execute(w, obj); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// printYAML writes the report, or a single PodReport, as yaml using the
+// json field names
+func printYAML(w io.Writer, v interface{}) error {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// SinglePodReport is a single pod written on its own, stamped with the
+// version of the report it came from so it can be read back as a baseline
+type SinglePodReport struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	PodReport
+}
+
+// serializable picks what -o json/yaml writes, a single pod is written as
+// the pod itself with its containers nested under it rather than a list
+func serializable(report Report, single bool) interface{} {
+	if single && len(report.Pods) == 1 {
+		pod := SinglePodReport{APIVersion: report.APIVersion, PodReport: report.Pods[0]}
+		if report.Kind != "" {
+			pod.Kind = podReportKind
+		}
+		return pod
+	}
+	return report
+}
+
+// printNames writes a pod/<name> line per pod like kubectl -o name, pods are
+// only listed once no matter how many containers they have
+func printNames(w io.Writer, podData []podqos.PodData) {
+	for _, p := range podData {
+		fmt.Fprintf(w, "pod/%s\n", p.PodName)
+	}
+}
+
+// printEnv writes the pod counts per class as shell assignments, e.g.
+// PODQOS_GUARANTEED=3, so a script can eval or source them
+func printEnv(w io.Writer, podData []podqos.PodData, resources []v1.ResourceName) {
+	counts := classCounts(podData, resources)
+	for _, class := range []podqos.PodQosPolicy{podqos.Guaranteed, podqos.Burstable, podqos.BestEffort} {
+		fmt.Fprintf(w, "PODQOS_%s=%d\n", strings.ToUpper(string(class)), counts[class])
+	}
+	fmt.Fprintf(w, "PODQOS_TOTAL=%d\n", len(podData))
+}
+
+// classAnnotation is set on the pods written with --kubectl-compat
+const classAnnotation = "podqos.jdambly.github.io/class"
+
+// kubectlList mirrors the List wrapper kubectl prints for -o json
+type kubectlList struct {
+	Kind       string   `json:"kind"`
+	APIVersion string   `json:"apiVersion"`
+	Items      []v1.Pod `json:"items"`
+}
+
+// newKubectlList wraps the original pods in a kubectl style List, each pod
+// annotated with its computed class so tools that expect kubectl output can
+// consume it
+func newKubectlList(podData []podqos.PodData, resources []v1.ResourceName) kubectlList {
+	list := kubectlList{Kind: "List", APIVersion: "v1", Items: []v1.Pod{}}
+	for _, p := range podData {
+		if p.Pod == nil {
+			continue
+		}
+		pod := p.Pod.DeepCopy()
+		pod.Kind = "Pod"
+		pod.APIVersion = "v1"
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[classAnnotation] = string(p.QosClass(resources))
+		list.Items = append(list.Items, *pod)
+	}
+	return list
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestPrintNames(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	var buf bytes.Buffer
+	printNames(&buf, podData)
+	if got, want := buf.String(), "pod/web\npod/db\n"; got != want {
+		t.Errorf("printNames = %q, want a line per pod %q", got, want)
+	}
+}
+
+func TestPrintNamesAfterFilters(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", bestEffortContainer("app"), bestEffortContainer("proxy")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	only, err := parseOnly("besteffort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printNames(&buf, filterContainers(podData, only, cpuMemory))
+	if got := buf.String(); got != "pod/web\n" {
+		t.Errorf("printNames = %q, want only the BestEffort pod once", got)
+	}
+}
+
+func TestSinglePodNestsContainers(t *testing.T) {
+	pod := newPod("default", "web", guaranteedContainer("app"), burstableContainer("sidecar"))
+	report := newReport(toPodData(pod), cpuMemory)
+
+	var buf bytes.Buffer
+	if err := printJSON(&buf, versionedReport(report, "v1alpha1", true), false); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["name"] != "web" || got["namespace"] != "default" {
+		t.Errorf("single pod = %v, want the pod itself", got)
+	}
+	if got["apiVersion"] != reportGroup+"/v1alpha1" || got["kind"] != podReportKind {
+		t.Errorf("single pod is stamped %v %v, want %s/v1alpha1 %s", got["apiVersion"], got["kind"], reportGroup, podReportKind)
+	}
+	if _, ok := got["pods"]; ok {
+		t.Error("single pod has a pods list")
+	}
+	containers, ok := got["containers"].([]interface{})
+	if !ok || len(containers) != 2 {
+		t.Fatalf("containers = %v, want an array of 2", got["containers"])
+	}
+	if c := containers[1].(map[string]interface{}); c["name"] != "sidecar" || c["class"] != "Burstable" {
+		t.Errorf("second container = %v, want sidecar Burstable", c)
+	}
+
+	// more than one pod stays a list even when single
+	report = newReport(toPodData(pod, newPod("default", "db", bestEffortContainer("pg"))), cpuMemory)
+	if _, ok := serializable(report, true).(Report); !ok {
+		t.Error("two pods aren't written as a report")
+	}
+}
+
+func TestSinglePodReadsBackAsBaseline(t *testing.T) {
+	pod := newPod("default", "web", guaranteedContainer("app"))
+	report := newReport(toPodData(pod), cpuMemory)
+	var buf bytes.Buffer
+	if err := printJSON(&buf, versionedReport(report, "v1alpha1", true), false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "web.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := loadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := diffReports(baseline, report); len(changes) != 0 {
+		t.Errorf("diff against its own single pod report = %v, want none", changes)
+	}
+}
+
+func TestKubectlList(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "db", guaranteedContainer("pg")))
+	var buf bytes.Buffer
+	if err := printJSON(&buf, newKubectlList(podData, cpuMemory), false); err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Kind       string   `json:"kind"`
+		APIVersion string   `json:"apiVersion"`
+		Items      []v1.Pod `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Kind != "List" || list.APIVersion != "v1" || len(list.Items) != 2 {
+		t.Fatalf("list = %s %s with %d items, want a v1 List of 2", list.APIVersion, list.Kind, len(list.Items))
+	}
+	for i, want := range []string{"Burstable", "Guaranteed"} {
+		pod := list.Items[i]
+		if pod.Kind != "Pod" || pod.Annotations[classAnnotation] != want {
+			t.Errorf("item %d = %s annotated %q, want a Pod annotated %s", i, pod.Kind, pod.Annotations[classAnnotation], want)
+		}
+	}
+	// the collected pods are left as they were
+	if podData[0].Pod.Annotations[classAnnotation] != "" {
+		t.Error("newKubectlList annotated the collected pod")
+	}
+}
+
+func TestPrintJSONCompact(t *testing.T) {
+	report := newReport(toPodData(newPod("default", "web", guaranteedContainer("app"))), cpuMemory)
+	var indented, compact bytes.Buffer
+	if err := printJSON(&indented, report, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := printJSON(&compact, report, true); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(indented.String(), "\n") < 2 || !strings.Contains(indented.String(), "\n  \"") {
+		t.Errorf("indented json = %q, want a field per line indented by two spaces", indented.String())
+	}
+	if got := compact.String(); strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") || strings.Contains(got, "  ") {
+		t.Errorf("compact json = %q, want a single line", got)
+	}
+	// both are the same report
+	var a, b Report
+	if err := json.Unmarshal(indented.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(compact.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Pods) != 1 || len(b.Pods) != 1 || a.Pods[0].Name != b.Pods[0].Name {
+		t.Errorf("compact json reads back as %v, want %v", b, a)
+	}
+}
+
+func TestPrintJSONPath(t *testing.T) {
+	report := newReport(toPodData(
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "db", guaranteedContainer("pg"))), cpuMemory)
+	for template, want := range map[string]string{
+		"{.pods[*].name}":                    "web db\n",
+		"{.pods[0].containers[0].class}":     "Burstable\n",
+		`{range .pods[*]}{.name}{"\n"}{end}`: "web\ndb\n\n",
+	} {
+		j, err := parseJSONPath(template)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) = %v", template, err)
+		}
+		var buf bytes.Buffer
+		if err := printJSONPath(&buf, j, report); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("%s = %q, want %q", template, got, want)
+		}
+	}
+	if _, err := parseJSONPath("{.pods[*].name"); err == nil || !strings.Contains(err.Error(), "invalid jsonpath template") {
+		t.Errorf("parseJSONPath of an unclosed template = %v, want invalid jsonpath template", err)
+	}
+}
+
+func TestPrintEnv(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "db", guaranteedContainer("pg")),
+		newPod("default", "cache", guaranteedContainer("redis")),
+		newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy")),
+		newPod("default", "batch", bestEffortContainer("job")))
+	var buf bytes.Buffer
+	printEnv(&buf, podData, cpuMemory)
+	got := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			t.Fatalf("line %q isn't KEY=VALUE", line)
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		got[parts[0]] = n
+	}
+	want := map[string]int{"PODQOS_GUARANTEED": 2, "PODQOS_BURSTABLE": 1, "PODQOS_BESTEFFORT": 1, "PODQOS_TOTAL": 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("env = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// nopCloser keeps stdout open when the report isn't written to a file
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// gzipFile closes the gzip stream before the file so the trailer is written
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+// openOutput opens where the report is written, stdout when path is empty.
+// The file is gzipped when compress is set or the path ends in .gz
+func openOutput(path string, compress bool) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if compress || strings.HasSuffix(path, ".gz") {
+		return gzipFile{Writer: gzip.NewWriter(f), f: f}, nil
+	}
+	return f, nil
+}
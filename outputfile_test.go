@@ -0,0 +1,83 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeReport writes the report of a single pod to the output
+func writeReport(t *testing.T, path string, compress bool) {
+	out, err := openOutput(path, compress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := newReport(toPodData(newPod("default", "web", burstableContainer("app"))), cpuMemory)
+	if err := printJSON(out, report, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readGzipReport reads the report back through gzip
+func readGzipReport(t *testing.T, path string) Report {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.NewDecoder(zr).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestOutputFileGzip(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name     string
+		compress bool
+	}{{"report.json.gz", false}, {"report.json", true}} {
+		path := filepath.Join(dir, tt.name)
+		writeReport(t, path, tt.compress)
+		report := readGzipReport(t, path)
+		if len(report.Pods) != 1 || report.Pods[0].Name != "web" {
+			t.Errorf("%s read back %+v, want the web pod", tt.name, report)
+		}
+	}
+}
+
+func TestOutputFilePlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	writeReport(t, path, false)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Errorf("plain file isn't json: %v", err)
+	}
+}
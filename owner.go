@@ -0,0 +1,65 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ownerResolver follows pod owner references up to their workload, lookups
+// are cached so every pod of a ReplicaSet shares one Get, and with a disk
+// cache later runs skip the Get too
+type ownerResolver struct {
+	clientset kubernetes.Interface
+	cache     *objectCache
+	disk      *ownerDiskCache
+}
+
+func newOwnerResolver(clientset kubernetes.Interface, disk *ownerDiskCache) *ownerResolver {
+	return &ownerResolver{clientset: clientset, cache: newObjectCache(), disk: disk}
+}
+
+// workload returns the kind and name of the workload owning a pod, pods of
+// a Deployment are owned by a ReplicaSet so that one extra hop is needed
+func (r *ownerResolver) workload(ctx context.Context, namespace string, owner *metav1.OwnerReference) (kind, name string, err error) {
+	if owner == nil {
+		return "", "", nil
+	}
+	if owner.Kind != "ReplicaSet" {
+		return owner.Kind, owner.Name, nil
+	}
+	obj, err := r.cache.get(namespace, owner.Kind, owner.Name, func() (interface{}, error) {
+		if controller, ok := r.disk.get(owner.UID); ok {
+			return controller, nil
+		}
+		rs, err := r.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		// only the controller is needed, don't hold on to the whole object
+		controller := metav1.GetControllerOf(rs)
+		r.disk.put(rs.UID, controller)
+		return controller, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if deployment := obj.(*metav1.OwnerReference); deployment != nil {
+		return deployment.Kind, deployment.Name, nil
+	}
+	return owner.Kind, owner.Name, nil
+}
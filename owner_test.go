@@ -0,0 +1,84 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// countGets counts the gets of the resource made through the clientset
+func countGets(clientset *fake.Clientset, resource string) *int64 {
+	var gets int64
+	clientset.PrependReactor("get", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt64(&gets, 1)
+		return false, nil, nil
+	})
+	return &gets
+}
+
+func TestOwnerLookupsAreCached(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newReplicaSet("default", "web-abc", "web"))
+	gets := countGets(clientset, "replicasets")
+	var podData []*v1.Pod
+	for _, name := range []string{"web-abc-1", "web-abc-2", "web-abc-3", "web-abc-4", "web-abc-5"} {
+		pod := newPod("default", name, bestEffortContainer("app"))
+		ownedBy(pod, "ReplicaSet", "web-abc")
+		podData = append(podData, pod)
+	}
+
+	owners := newOwnerResolver(clientset, nil)
+	var wg sync.WaitGroup
+	for _, p := range toPodData(podData...) {
+		wg.Add(1)
+		go func(p podqos.PodData) {
+			defer wg.Done()
+			kind, name, err := owners.workload(context.Background(), p.NameSpace, p.Owner)
+			if err != nil || kind != "Deployment" || name != "web" {
+				t.Errorf("%s workload = %s/%s %v, want Deployment/web", p.PodName, kind, name, err)
+			}
+		}(p)
+	}
+	wg.Wait()
+	if *gets != 1 {
+		t.Errorf("the ReplicaSet was fetched %d times for 5 pods, want once", *gets)
+	}
+}
+
+func TestOwnerWithoutReplicaSet(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	gets := countGets(clientset, "replicasets")
+	owners := newOwnerResolver(clientset, nil)
+	job := newPod("default", "backup-1", bestEffortContainer("backup"))
+	ownedBy(job, "Job", "backup")
+
+	p := toPodData(job)[0]
+	if kind, name, err := owners.workload(context.Background(), p.NameSpace, p.Owner); err != nil || kind != "Job" || name != "backup" {
+		t.Errorf("workload = %s/%s %v, want Job/backup", kind, name, err)
+	}
+	if kind, name, err := owners.workload(context.Background(), "default", nil); err != nil || kind != "" || name != "" {
+		t.Errorf("workload of a bare pod = %s/%s %v, want none", kind, name, err)
+	}
+	if *gets != 0 {
+		t.Errorf("%d gets, want none", *gets)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// OwnerTotal is the footprint of a workload, the effective requests and
+// limits of all its pods added up. A resource is left out of Limits when any
+// of the pods is unbounded on it
+type OwnerTotal struct {
+	Context   string
+	NameSpace string
+	// Kind and Name are the workload, bare pods are their own owner
+	Kind     string
+	Name     string
+	Pods     int
+	Limits   podqos.ResourceData
+	Requests podqos.ResourceData
+}
+
+// ownerTotals adds up the pods per owning workload, ReplicaSets are
+// followed to their Deployment when owners is set. The result is sorted by
+// cpu request, largest first
+func ownerTotals(ctx context.Context, podData []podqos.PodData, owners *ownerResolver) ([]OwnerTotal, error) {
+	var totals []OwnerTotal
+	index := map[string]int{}
+	for _, p := range podData {
+		kind, name := "Pod", p.PodName
+		if p.Owner != nil {
+			kind, name = p.Owner.Kind, p.Owner.Name
+			if owners != nil {
+				var err error
+				if kind, name, err = owners.workload(ctx, p.NameSpace, p.Owner); err != nil {
+					return nil, err
+				}
+			}
+		}
+		key := p.Context + "/" + workloadKey(p.NameSpace, kind, name)
+		i, ok := index[key]
+		if !ok {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, OwnerTotal{
+				Context:   p.Context,
+				NameSpace: p.NameSpace,
+				Kind:      kind,
+				Name:      name,
+				Limits:    podqos.ResourceData{},
+				Requests:  podqos.ResourceData{},
+			})
+		}
+		totals[i].Pods++
+		requests, limits := p.EffectiveResources()
+		totals[i].Requests.Add(requests)
+		if totals[i].Pods == 1 {
+			totals[i].Limits.Add(limits)
+			continue
+		}
+		// the workload is unbounded on a resource as soon as one of its pods is
+		for resourceName, total := range totals[i].Limits {
+			q, ok := limits[resourceName]
+			if !ok {
+				delete(totals[i].Limits, resourceName)
+				continue
+			}
+			total.Add(q)
+			totals[i].Limits[resourceName] = total
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		return totals[i].Requests.CPU().Cmp(*totals[j].Requests.CPU()) > 0
+	})
+	return totals, nil
+}
+
+// printOwnerTotals writes one row per workload with its pods and total
+// requests and limits of cpu and memory, unbounded for a missing limit
+func printOwnerTotals(w io.Writer, totals []OwnerTotal, style format.Style, showContext bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAMESPACE", "OWNER", "PODS", "CPUr", "CPUl", "MEMr", "MEMl"}
+	if showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, t := range totals {
+		row := []string{t.NameSpace, strings.ToLower(t.Kind) + "/" + t.Name, strconv.Itoa(t.Pods)}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			row = append(row, format.Resource(style, name, t.Requests.Get(name)), limitCell(style, t.Limits, name))
+		}
+		if showContext {
+			row = append([]string{t.Context}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOwnerTotals(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newReplicaSet("default", "web-abc", "web"))
+	var pods []*v1.Pod
+	for _, name := range []string{"web-abc-1", "web-abc-2", "web-abc-3"} {
+		pod := newPod("default", name, burstableContainer("app"))
+		ownedBy(pod, "ReplicaSet", "web-abc")
+		pods = append(pods, pod)
+	}
+	job := newPod("default", "backup-1", newContainer("backup", nil, quantities("cpu", "100m")))
+	ownedBy(job, "Job", "backup")
+	pods = append(pods, job, newPod("default", "db", guaranteedContainer("pg")))
+
+	totals, err := ownerTotals(context.Background(), toPodData(pods...), newOwnerResolver(clientset, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printOwnerTotals(&buf, totals, format.Human, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"NAMESPACE OWNER PODS CPUr CPUl MEMr MEMl",
+		"default pod/db 1 1 1 1Gi 1Gi",
+		"default deployment/web 3 750m 3 384Mi 3Gi",
+		"default job/backup 1 100m unbounded 0 unbounded",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("totals = %q", lines)
+	}
+	for i, line := range lines {
+		if got := strings.Join(strings.Fields(line), " "); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestOwnerTotalsUseEffectiveResources(t *testing.T) {
+	var pods []*v1.Pod
+	for _, name := range []string{"worker-1", "worker-2"} {
+		pod := newPod("default", name, guaranteedContainer("app"))
+		ownedBy(pod, "StatefulSet", "worker")
+		pods = append(pods, pod)
+	}
+	// the init container of the first pod needs more than its app, the
+	// second pod has no memory limit
+	pods[0].Spec.InitContainers = []v1.Container{newContainer("warm", quantities("cpu", "2"), quantities("cpu", "2"))}
+	pods[1].Spec.Containers[0].Resources.Limits = quantities("cpu", "1")
+
+	totals, err := ownerTotals(context.Background(), toPodData(pods...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printOwnerTotals(&buf, totals, format.Human, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Join(strings.Fields(lines[len(lines)-1]), " "); got != "default statefulset/worker 2 3 3 2Gi unbounded" {
+		t.Errorf("totals = %q, want the init container counted and memory unbounded", got)
+	}
+}
@@ -0,0 +1,492 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podqos collects the cpu/memory requests and limits of pods and
+// computes their QoS class. It only needs a kubernetes.Interface so it can be
+// embedded in controllers and operators that bring their own client
+package podqos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// NewClient builds a clientset from a rest.Config, for embedders that
+// already have one instead of a kubeconfig
+func NewClient(cfg *rest.Config) (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(cfg)
+}
+
+// ResourceData holds the quantity of each resource set on a container,
+// keyed by resource name
+type ResourceData map[v1.ResourceName]resource.Quantity
+
+// Get returns the quantity of the resource, zero when it isn't set
+func (r ResourceData) Get(name v1.ResourceName) *resource.Quantity {
+	if q, ok := r[name]; ok {
+		return &q
+	}
+	return &resource.Quantity{Format: resource.DecimalSI}
+}
+
+// Has reports whether the resource is set at all, an explicit "0" is set
+// while a missing entry is not
+func (r ResourceData) Has(name v1.ResourceName) bool {
+	_, ok := r[name]
+	return ok
+}
+
+// CPU returns the cpu quantity
+func (r ResourceData) CPU() *resource.Quantity {
+	return r.Get(v1.ResourceCPU)
+}
+
+// Memory returns the memory quantity
+func (r ResourceData) Memory() *resource.Quantity {
+	return r.Get(v1.ResourceMemory)
+}
+
+// Add adds every quantity of other to r
+func (r ResourceData) Add(other ResourceData) {
+	for name, q := range other {
+		sum := r.Get(name)
+		sum.Add(q)
+		r[name] = *sum
+	}
+}
+
+// ContainerData holds container information
+type ContainerData struct {
+	Name     string       `json:"name"`
+	Limits   ResourceData `json:"limits,omitempty"`
+	Requests ResourceData `json:"requests,omitempty"`
+	// Usage is what the container currently uses according to the metrics
+	// api, only filled in with --with-usage
+	Usage ResourceData `json:"usage,omitempty"`
+	// Class overrides the computed class when set, e.g. with the class the
+	// node actually realized
+	Class PodQosPolicy `json:"class,omitempty"`
+	// Sidecar is set on init containers with restartPolicy Always, native
+	// sidecars that keep running next to the app containers
+	Sidecar bool `json:"sidecar,omitempty"`
+}
+
+// PodData holds pod information, and list of containers in pod. It
+// marshals to json and back to an equal value, quantities use their
+// canonical string form, except Pod which is left out
+type PodData struct {
+	Context        string            `json:"context,omitempty"`
+	PodName        string            `json:"name"`
+	NameSpace      string            `json:"namespace"`
+	Containers     []ContainerData   `json:"containers"`
+	InitContainers []ContainerData   `json:"initContainers,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+	// Owner is the controller of the pod, nil for bare pods
+	Owner *metav1.OwnerReference `json:"owner,omitempty"`
+	// HPA names the autoscaler of the owning workload, only filled in with --with-hpa
+	HPA string `json:"hpa,omitempty"`
+	// NodeName is empty until the pod is scheduled
+	NodeName string `json:"nodeName,omitempty"`
+	// NodeStatus says whether the node is cordoned or tainted, only filled in with --with-node-status
+	NodeStatus string `json:"nodeStatus,omitempty"`
+	// NodeAllocatable is what the node can hand out to pods, only filled in with --percent-of-node
+	NodeAllocatable ResourceData `json:"nodeAllocatable,omitempty"`
+	// OS is the operating system the pod runs on, only filled in with --show-os
+	OS string `json:"os,omitempty"`
+	// NodePressure lists the pressure conditions of the node, e.g.
+	// MemoryPressure, only filled in with --node-pressure
+	NodePressure []string `json:"nodePressure,omitempty"`
+	// PodLimits and PodRequests are the pod-level resources of
+	// spec.resources, they win over the sum of the containers for every
+	// resource they set
+	PodLimits   ResourceData `json:"podLimits,omitempty"`
+	PodRequests ResourceData `json:"podRequests,omitempty"`
+	// Pod is the object the data was extracted from
+	Pod *v1.Pod `json:"-"`
+}
+
+// PodQosPolicy describes the QosClass for each container
+// see: https://kubernetes.io/docs/tasks/administer-cluster/cpu-management-policies/
+// for more information
+type PodQosPolicy string
+
+const (
+	// BestEffort class when no resource requests or limits are specified.
+	BestEffort PodQosPolicy = "BestEffort"
+
+	// Burstable class when requests are less then limits
+	Burstable PodQosPolicy = "Burstable"
+
+	// Guaranteed class when requests are equal to limits
+	Guaranteed PodQosPolicy = "Guaranteed"
+)
+
+// DefaultResources only looks at cpu, which is what the class was always
+// computed from
+var DefaultResources = []v1.ResourceName{v1.ResourceCPU}
+
+// KubeletResources are what kubelet computes status.qosClass from, the
+// class it evicts by whatever --resources shows
+var KubeletResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+
+// resourceQosClass classifies the container on a single resource. A request
+// without a limit is unbounded so it is Burstable, and a limit without a
+// request gets the limit as its request, the same defaulting the api server
+// does
+func (c *ContainerData) resourceQosClass(name v1.ResourceName) PodQosPolicy {
+	limit := c.Limits.Get(name).MilliValue()
+	request := c.Requests.Get(name).MilliValue()
+
+	if limit == 0 && request == 0 {
+		return BestEffort
+	}
+	if !c.Limits.Has(name) {
+		return Burstable
+	}
+	if !c.Requests.Has(name) {
+		return Guaranteed
+	}
+	if limit == request {
+		return Guaranteed
+	}
+	if request < limit {
+		return Burstable
+	}
+	return BestEffort
+}
+
+// QosClass classifies the container on the given resources, it is
+// Guaranteed or BestEffort only when every resource agrees and Burstable
+// otherwise. A Class set on the container wins over the resources
+func (c *ContainerData) QosClass(resources []v1.ResourceName) PodQosPolicy {
+	if c.Class != "" {
+		return c.Class
+	}
+	if len(resources) == 0 {
+		resources = DefaultResources
+	}
+	class := c.resourceQosClass(resources[0])
+	for _, name := range resources[1:] {
+		if c.resourceQosClass(name) != class {
+			return Burstable
+		}
+	}
+	return class
+}
+
+// QosClass classifies the pod from its containers the same way a container
+// is classified from its resources, Guaranteed or BestEffort only when every
+// container agrees and Burstable otherwise. Init containers count too, like
+// they do for kubelet. A pod with pod-level resources is classified from
+// its effective resources as a whole instead, unless its containers carry
+// a Class
+func (p *PodData) QosClass(resources []v1.ResourceName) PodQosPolicy {
+	if p.HasPodLevelResources() && (len(p.Containers) == 0 || p.Containers[0].Class == "") {
+		requests, limits := p.EffectiveResources()
+		pod := ContainerData{Limits: limits, Requests: requests}
+		return pod.QosClass(resources)
+	}
+	var class PodQosPolicy
+	for _, containers := range [][]ContainerData{p.Containers, p.InitContainers} {
+		for _, c := range containers {
+			containerClass := c.QosClass(resources)
+			if class == "" {
+				class = containerClass
+			} else if containerClass != class {
+				return Burstable
+			}
+		}
+	}
+	if class == "" {
+		return BestEffort
+	}
+	return class
+}
+
+// NewContainerData copies the limits and requests out of a container spec
+func NewContainerData(container v1.Container) ContainerData {
+	return ContainerData{
+		Name:     container.Name,
+		Limits:   ResourceData(container.Resources.Limits.DeepCopy()),
+		Requests: ResourceData(container.Resources.Requests.DeepCopy()),
+		Sidecar:  container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways,
+	}
+}
+
+// NewPodData builds the PodData for a single pod, including its init containers
+func NewPodData(pod v1.Pod) PodData {
+	var containers []ContainerData
+	for _, container := range pod.Spec.Containers {
+		containers = append(containers, NewContainerData(container))
+	}
+	var initContainers []ContainerData
+	for _, container := range pod.Spec.InitContainers {
+		initContainers = append(initContainers, NewContainerData(container))
+	}
+	data := PodData{
+		PodName:        pod.Name,
+		NameSpace:      pod.Namespace,
+		Containers:     containers,
+		InitContainers: initContainers,
+		Labels:         pod.Labels,
+		Annotations:    pod.Annotations,
+		Owner:          metav1.GetControllerOf(&pod),
+		NodeName:       pod.Spec.NodeName,
+		Pod:            &pod,
+	}
+	if pod.Spec.Resources != nil {
+		data.PodLimits = ResourceData(pod.Spec.Resources.Limits.DeepCopy())
+		data.PodRequests = ResourceData(pod.Spec.Resources.Requests.DeepCopy())
+	}
+	return data
+}
+
+// HasPodLevelResources reports whether the pod sets requests or limits in
+// spec.resources, the pod-level resources of Kubernetes 1.32
+func (p *PodData) HasPodLevelResources() bool {
+	return len(p.PodLimits) > 0 || len(p.PodRequests) > 0
+}
+
+// withPodLevel overrides the resources the pod sets at the pod level
+func withPodLevel(resources, podLevel ResourceData) {
+	for name, q := range podLevel {
+		resources[name] = q.DeepCopy()
+	}
+}
+
+// NewTemplateData builds the PodData for the pod template of a workload, so
+// its class can be checked before anything is rolled out. The name is what
+// shows up as the pod name, e.g. "deployment/web"
+func NewTemplateData(namespace, name string, template v1.PodTemplateSpec) PodData {
+	pod := v1.Pod{ObjectMeta: *template.ObjectMeta.DeepCopy(), Spec: *template.Spec.DeepCopy()}
+	pod.Name = name
+	pod.Namespace = namespace
+	return NewPodData(pod)
+}
+
+// NewWorkload returns an empty Deployment, StatefulSet, DaemonSet or Job for
+// the kind, matched case insensitively and taking the kubectl short names
+// too. It is nil for any other kind
+func NewWorkload(kind string) runtime.Object {
+	switch strings.ToLower(kind) {
+	case "deployment", "deployments", "deploy":
+		return &appsv1.Deployment{}
+	case "statefulset", "statefulsets", "sts":
+		return &appsv1.StatefulSet{}
+	case "daemonset", "daemonsets", "ds":
+		return &appsv1.DaemonSet{}
+	case "job", "jobs":
+		return &batchv1.Job{}
+	}
+	return nil
+}
+
+// WorkloadTemplate returns the pod template of a workload made by
+// NewWorkload, named like --workload names it, e.g. "deployment/web"
+func WorkloadTemplate(obj runtime.Object) (PodData, bool) {
+	var (
+		kind     string
+		meta     metav1.ObjectMeta
+		template v1.PodTemplateSpec
+	)
+	switch obj := obj.(type) {
+	case *appsv1.Deployment:
+		kind, meta, template = "deployment", obj.ObjectMeta, obj.Spec.Template
+	case *appsv1.StatefulSet:
+		kind, meta, template = "statefulset", obj.ObjectMeta, obj.Spec.Template
+	case *appsv1.DaemonSet:
+		kind, meta, template = "daemonset", obj.ObjectMeta, obj.Spec.Template
+	case *batchv1.Job:
+		kind, meta, template = "job", obj.ObjectMeta, obj.Spec.Template
+	default:
+		return PodData{}, false
+	}
+	return NewTemplateData(meta.Namespace, kind+"/"+meta.Name, template), true
+}
+
+// CollectWorkload gets a Deployment, StatefulSet, DaemonSet or Job and
+// extracts its pod template, kind is matched the way NewWorkload does
+func CollectWorkload(clientset kubernetes.Interface, namespace, kind, name string) ([]PodData, error) {
+	var obj runtime.Object
+	var err error
+	switch NewWorkload(kind).(type) {
+	case *appsv1.Deployment:
+		obj, err = clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case *appsv1.StatefulSet:
+		obj, err = clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case *appsv1.DaemonSet:
+		obj, err = clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case *batchv1.Job:
+		obj, err = clientset.BatchV1().Jobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q, use deployment, statefulset, daemonset or job", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	podData, _ := WorkloadTemplate(obj)
+	return []PodData{podData}, nil
+}
+
+// listChunkSize is how many pods are requested per List call, the same
+// default kubectl uses
+const listChunkSize = 500
+
+// CollectPodData lists the pods in the namespace and extracts their resources,
+// an empty namespace lists all namespaces
+func CollectPodData(clientset kubernetes.Interface, namespace string) ([]PodData, error) {
+	podData, _, err := CollectPods(clientset, namespace, 0)
+	return podData, err
+}
+
+// CollectPods lists the pods in chunks, stopping once maxPods have been
+// collected. Zero means no limit, truncated is set when pods were left out
+func CollectPods(clientset kubernetes.Interface, namespace string, maxPods int) (podData []PodData, truncated bool, err error) {
+	return CollectPodsMatching(clientset, namespace, "", maxPods)
+}
+
+// CollectPodsMatching is CollectPods for only the pods matching the field
+// selector, e.g. metadata.namespace!=kube-system
+func CollectPodsMatching(clientset kubernetes.Interface, namespace, fieldSelector string, maxPods int) (podData []PodData, truncated bool, err error) {
+	return CollectPodsMatchingContext(context.TODO(), clientset, namespace, fieldSelector, maxPods)
+}
+
+// CollectPodsMatchingContext is CollectPodsMatching stopping when ctx is
+// done. The pods listed before that are returned along with the error so a
+// caller can still show them
+func CollectPodsMatchingContext(ctx context.Context, clientset kubernetes.Interface, namespace, fieldSelector string, maxPods int) (podData []PodData, truncated bool, err error) {
+	return CollectPodsWithProgress(ctx, clientset, namespace, fieldSelector, maxPods, nil)
+}
+
+// CollectPodsWithProgress is CollectPodsMatchingContext calling onPage after
+// every chunk with the pods listed so far and the api server's estimate of
+// how many are left, zero when it gives none. A nil onPage isn't called
+func CollectPodsWithProgress(ctx context.Context, clientset kubernetes.Interface, namespace, fieldSelector string, maxPods int, onPage func(listed, remaining int)) (podData []PodData, truncated bool, err error) {
+	opts := metav1.ListOptions{Limit: listChunkSize, FieldSelector: fieldSelector}
+	for {
+		if maxPods > 0 && maxPods-len(podData) < listChunkSize {
+			opts.Limit = int64(maxPods - len(podData))
+		}
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return podData, false, err
+			}
+			return nil, false, err
+		}
+		// loop through the pods, and for each pod get the resources
+		for _, pod := range pods.Items { // don't forget _ is there to ignore the index of the list
+			if maxPods > 0 && len(podData) == maxPods {
+				return podData, true, nil
+			}
+			podData = append(podData, NewPodData(pod))
+		}
+		if onPage != nil {
+			remaining := 0
+			if pods.RemainingItemCount != nil {
+				remaining = int(*pods.RemainingItemCount)
+			}
+			onPage(len(podData), remaining)
+		}
+		if pods.Continue == "" {
+			return podData, false, nil
+		}
+		if maxPods > 0 && len(podData) >= maxPods {
+			return podData, true, nil
+		}
+		opts.Continue = pods.Continue
+	}
+}
+
+// CollectPod gets a single pod by name
+func CollectPod(clientset kubernetes.Interface, namespace, name string) ([]PodData, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return []PodData{NewPodData(*pod)}, nil
+}
+
+// EffectiveResources computes what the scheduler accounts for the pod, for
+// each resource that is the larger of the sum over the app containers and
+// the biggest single init container, since init containers run one at a time
+// before the app containers start. Native sidecars keep running once
+// started, so they add to the app containers and to every init container
+// after them, the same way kubelet counts them. A resource that any
+// container has no limit for is left out of limits, the pod as a whole is
+// unbounded on it. Pod-level resources replace all of that for the
+// resources they set
+func (p *PodData) EffectiveResources() (requests, limits ResourceData) {
+	requests, limits = ResourceData{}, ResourceData{}
+	for _, c := range p.Containers {
+		requests.Add(c.Requests)
+		limits.Add(c.Limits)
+	}
+	// sidecarRequests and sidecarLimits sum the sidecars started so far
+	sidecarRequests, sidecarLimits := ResourceData{}, ResourceData{}
+	initRequests, initLimits := ResourceData{}, ResourceData{}
+	for _, c := range p.InitContainers {
+		if c.Sidecar {
+			sidecarRequests.Add(c.Requests)
+			sidecarLimits.Add(c.Limits)
+			maxResources(initRequests, sidecarRequests)
+			maxResources(initLimits, sidecarLimits)
+			continue
+		}
+		running := ResourceData{}
+		running.Add(sidecarRequests)
+		running.Add(c.Requests)
+		maxResources(initRequests, running)
+		running = ResourceData{}
+		running.Add(sidecarLimits)
+		running.Add(c.Limits)
+		maxResources(initLimits, running)
+	}
+	requests.Add(sidecarRequests)
+	limits.Add(sidecarLimits)
+	maxResources(requests, initRequests)
+	maxResources(limits, initLimits)
+	for _, containers := range [][]ContainerData{p.Containers, p.InitContainers} {
+		for _, c := range containers {
+			for name := range limits {
+				if _, ok := c.Limits[name]; !ok {
+					delete(limits, name)
+				}
+			}
+		}
+	}
+	withPodLevel(requests, p.PodRequests)
+	withPodLevel(limits, p.PodLimits)
+	return requests, limits
+}
+
+// maxResources keeps the larger quantity of each resource in total
+func maxResources(total, other ResourceData) {
+	for name, q := range other {
+		if current, ok := total[name]; !ok || q.Cmp(current) > 0 {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
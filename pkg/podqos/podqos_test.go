@@ -0,0 +1,450 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package podqos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// quantities builds a resource list from name, quantity pairs
+func quantities(pairs ...string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
+
+func containerData(limits, requests v1.ResourceList) ContainerData {
+	return NewContainerData(v1.Container{Name: "c", Resources: v1.ResourceRequirements{Limits: limits, Requests: requests}})
+}
+
+func TestEffectiveResourcesWithNativeSidecars(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	container := func(name, cpu string, policy *v1.ContainerRestartPolicy) v1.Container {
+		list := quantities("cpu", cpu)
+		return v1.Container{Name: name, RestartPolicy: policy, Resources: v1.ResourceRequirements{Limits: list, Requests: list}}
+	}
+	pod := v1.Pod{Spec: v1.PodSpec{
+		InitContainers: []v1.Container{
+			container("setup", "500m", nil),
+			container("proxy", "200m", &always),
+			container("migrate", "1", nil),
+		},
+		Containers: []v1.Container{container("app", "1", nil)},
+	}}
+	data := NewPodData(pod)
+	if data.InitContainers[0].Sidecar || !data.InitContainers[1].Sidecar {
+		t.Fatalf("sidecars = %v %v, want only proxy", data.InitContainers[0].Sidecar, data.InitContainers[1].Sidecar)
+	}
+	requests, limits := data.EffectiveResources()
+	// the app runs next to the proxy, 1 + 200m, and so does migrate, also
+	// 1 + 200m, while setup ran on its own
+	if got := requests.CPU().String(); got != "1200m" {
+		t.Errorf("effective cpu request = %s, want 1200m", got)
+	}
+	if got := limits.CPU().String(); got != "1200m" {
+		t.Errorf("effective cpu limit = %s, want 1200m", got)
+	}
+
+	// without the sidecar, the biggest init container and the app tie at 1
+	pod.Spec.InitContainers = pod.Spec.InitContainers[:1]
+	data = NewPodData(pod)
+	if requests, _ := data.EffectiveResources(); requests.CPU().String() != "1" {
+		t.Errorf("effective cpu request without sidecar = %s, want 1", requests.CPU())
+	}
+}
+
+func TestPodQosClassCountsInitContainers(t *testing.T) {
+	resources := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+	guaranteed := containerData(quantities("cpu", "1", "memory", "1Gi"), quantities("cpu", "1", "memory", "1Gi"))
+	burstable := containerData(quantities("cpu", "1"), quantities("cpu", "100m"))
+	bestEffort := containerData(nil, nil)
+	tests := []struct {
+		name           string
+		containers     []ContainerData
+		initContainers []ContainerData
+		want           PodQosPolicy
+	}{
+		{"no containers", nil, nil, BestEffort},
+		{"guaranteed", []ContainerData{guaranteed}, nil, Guaranteed},
+		{"guaranteed with guaranteed init", []ContainerData{guaranteed}, []ContainerData{guaranteed}, Guaranteed},
+		{"guaranteed with burstable init", []ContainerData{guaranteed}, []ContainerData{burstable}, Burstable},
+		{"guaranteed with bare init", []ContainerData{guaranteed}, []ContainerData{bestEffort}, Burstable},
+		{"best effort with bare init", []ContainerData{bestEffort}, []ContainerData{bestEffort}, BestEffort},
+		{"mixed app containers", []ContainerData{guaranteed, bestEffort}, nil, Burstable},
+	}
+	for _, tt := range tests {
+		p := PodData{Containers: tt.containers, InitContainers: tt.initContainers}
+		if got := p.QosClass(resources); got != tt.want {
+			t.Errorf("%s: QosClass = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveResourcesWithLargeInitContainer(t *testing.T) {
+	pod := v1.Pod{Spec: v1.PodSpec{
+		InitContainers: []v1.Container{
+			{Name: "migrate", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "2", "memory", "4Gi"), Requests: quantities("cpu", "2", "memory", "2Gi")}},
+		},
+		Containers: []v1.Container{
+			{Name: "app", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "500m", "memory", "1Gi"), Requests: quantities("cpu", "500m", "memory", "512Mi")}},
+			{Name: "proxy", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "500m", "memory", "1Gi"), Requests: quantities("cpu", "250m", "memory", "3Gi")}},
+		},
+	}}
+	data := NewPodData(pod)
+	requests, limits := data.EffectiveResources()
+	// cpu is the init container's 2 over the app sum of 750m, memory the app
+	// sum of 3.5Gi over the init container's 2Gi
+	if requests.CPU().String() != "2" || requests.Memory().String() != "3584Mi" {
+		t.Errorf("effective requests = %s/%s, want 2/3584Mi", requests.CPU(), requests.Memory())
+	}
+	if limits.CPU().String() != "2" || limits.Memory().String() != "4Gi" {
+		t.Errorf("effective limits = %s/%s, want 2/4Gi", limits.CPU(), limits.Memory())
+	}
+}
+
+func TestEffectiveResourcesUnboundedLimits(t *testing.T) {
+	pod := v1.Pod{Spec: v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "setup", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "100m"), Requests: quantities("memory", "64Mi")}}},
+		Containers: []v1.Container{
+			{Name: "app", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "1", "memory", "1Gi")}},
+		},
+	}}
+	data := NewPodData(pod)
+	_, limits := data.EffectiveResources()
+	// setup has no memory limit, its cpu limit is below the app's
+	if _, ok := limits[v1.ResourceMemory]; ok || limits.CPU().String() != "1" {
+		t.Errorf("effective limits = %v, want cpu 1 and no memory limit", limits)
+	}
+}
+
+func TestQosClassOnResources(t *testing.T) {
+	c := containerData(quantities("cpu", "1", "nvidia.com/gpu", "1"), quantities("cpu", "1", "memory", "256Mi", "nvidia.com/gpu", "1"))
+	tests := []struct {
+		resources []v1.ResourceName
+		want      PodQosPolicy
+	}{
+		{nil, Guaranteed},
+		{[]v1.ResourceName{v1.ResourceCPU}, Guaranteed},
+		{[]v1.ResourceName{v1.ResourceMemory}, Burstable},
+		{[]v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}, Burstable},
+		{[]v1.ResourceName{v1.ResourceCPU, "nvidia.com/gpu"}, Guaranteed},
+		{[]v1.ResourceName{v1.ResourceEphemeralStorage}, BestEffort},
+	}
+	for _, tt := range tests {
+		if got := c.QosClass(tt.resources); got != tt.want {
+			t.Errorf("QosClass(%v) = %s, want %s", tt.resources, got, tt.want)
+		}
+	}
+}
+
+// listInChunks has the clientset page through the pods with limit and
+// continue like the api server does, it returns the number of list calls
+func listInChunks(clientset *fake.Clientset, pods []v1.Pod) *int {
+	calls := 0
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		opts := action.(k8stesting.ListActionImpl).ListOptions
+		start, _ := strconv.Atoi(opts.Continue)
+		end := len(pods)
+		if opts.Limit > 0 && start+int(opts.Limit) < end {
+			end = start + int(opts.Limit)
+		}
+		list := &v1.PodList{Items: pods[start:end]}
+		if end < len(pods) {
+			remaining := int64(len(pods) - end)
+			list.Continue, list.RemainingItemCount = strconv.Itoa(end), &remaining
+		}
+		return true, list, nil
+	})
+	return &calls
+}
+
+func TestCollectPodsStopsAtMaxPods(t *testing.T) {
+	var pods []v1.Pod
+	for i := 0; i < 10; i++ {
+		pods = append(pods, v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("web-%d", i)}})
+	}
+	clientset := fake.NewSimpleClientset()
+	calls := listInChunks(clientset, pods)
+
+	podData, truncated, err := CollectPods(clientset, "default", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 3 || !truncated {
+		t.Errorf("CollectPods = %d pods, truncated %v, want 3 truncated", len(podData), truncated)
+	}
+	if *calls != 1 {
+		t.Errorf("%d list calls, want one chunk of 3", *calls)
+	}
+
+	// without a limit every chunk is listed
+	podData, truncated, err = CollectPods(clientset, "default", 0)
+	if err != nil || len(podData) != 10 || truncated {
+		t.Errorf("CollectPods without a limit = %d pods, truncated %v, %v, want all 10", len(podData), truncated, err)
+	}
+	// exactly the limit isn't truncated
+	if _, truncated, _ = CollectPods(clientset, "default", 10); truncated {
+		t.Error("CollectPods of exactly 10 pods truncated, want not")
+	}
+}
+
+func TestCollectPodsReportsEveryChunk(t *testing.T) {
+	var pods []v1.Pod
+	for i := 0; i < 2*listChunkSize+1; i++ {
+		pods = append(pods, v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("web-%d", i)}})
+	}
+	clientset := fake.NewSimpleClientset()
+	listInChunks(clientset, pods)
+
+	var pages []string
+	podData, _, err := CollectPodsWithProgress(context.Background(), clientset, "", "", 0, func(listed, remaining int) {
+		pages = append(pages, fmt.Sprintf("%d+%d", listed, remaining))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(pages, " "), "500+501 1000+1 1001+0"; len(podData) != len(pods) || got != want {
+		t.Errorf("CollectPodsWithProgress = %d pods, pages %s, want %d pods, pages %s", len(podData), got, len(pods), want)
+	}
+}
+
+func TestCollectPodDataWithInjectedClient(t *testing.T) {
+	guaranteed := quantities("cpu", "500m", "memory", "256Mi")
+	clientset := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Limits: guaranteed, Requests: guaranteed}}}},
+		},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "db"}})
+
+	podData, err := CollectPodData(clientset, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podData) != 1 || podData[0].PodName != "web" || podData[0].Containers[0].Name != "app" {
+		t.Fatalf("CollectPodData = %+v, want the web pod", podData)
+	}
+	if got := podData[0].QosClass([]v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}); got != Guaranteed {
+		t.Errorf("QosClass = %s, want Guaranteed", got)
+	}
+	if podData, _ = CollectPodData(clientset, ""); len(podData) != 2 {
+		t.Errorf("CollectPodData of every namespace = %d pods, want 2", len(podData))
+	}
+
+	podData, err = CollectPod(clientset, "other", "db")
+	if err != nil || len(podData) != 1 || podData[0].PodName != "db" {
+		t.Errorf("CollectPod = %+v %v, want the db pod", podData, err)
+	}
+	if _, err := CollectPod(clientset, "default", "missing"); err == nil {
+		t.Error("CollectPod of a missing pod = nil error")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	clientset, err := NewClient(&rest.Config{Host: "https://cluster.example.com"})
+	if err != nil || clientset == nil {
+		t.Errorf("NewClient = %v %v, want a clientset", clientset, err)
+	}
+}
+
+func TestExplicitZeroRequest(t *testing.T) {
+	resources := []v1.ResourceName{v1.ResourceCPU}
+	// an absent request defaults to the limit, an explicit 0 doesn't
+	absent := containerData(quantities("cpu", "1"), nil)
+	zero := containerData(quantities("cpu", "1"), quantities("cpu", "0"))
+	if absent.Requests.Has(v1.ResourceCPU) || !zero.Requests.Has(v1.ResourceCPU) {
+		t.Fatalf("Has = %v/%v, want only the explicit 0 set", absent.Requests.Has(v1.ResourceCPU), zero.Requests.Has(v1.ResourceCPU))
+	}
+	if got := absent.QosClass(resources); got != Guaranteed {
+		t.Errorf("absent request QosClass = %s, want Guaranteed", got)
+	}
+	if got := zero.QosClass(resources); got != Burstable {
+		t.Errorf("explicit 0 request QosClass = %s, want Burstable", got)
+	}
+	if got := zero.Requests.CPU().String(); got != "0" {
+		t.Errorf("CPU() = %s, want 0", got)
+	}
+}
+
+func TestPodDataRoundTrip(t *testing.T) {
+	controller := true
+	in := PodData{
+		Context:   "prod",
+		PodName:   "web",
+		NameSpace: "default",
+		Containers: []ContainerData{
+			containerData(quantities("cpu", "1500m", "memory", "1536Mi"), quantities("cpu", "250m", "memory", "1G")),
+			{Name: "proxy", Class: Burstable, Usage: ResourceData(quantities("cpu", "12m"))},
+		},
+		InitContainers:  []ContainerData{{Name: "mesh", Sidecar: true, Requests: ResourceData(quantities("cpu", "100m"))}},
+		Labels:          map[string]string{"app": "web"},
+		Annotations:     map[string]string{"team": "payments"},
+		Owner:           &metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-abc", Controller: &controller},
+		HPA:             "web",
+		NodeName:        "node-1",
+		NodeStatus:      "Cordoned",
+		NodeAllocatable: ResourceData(quantities("cpu", "4", "memory", "16Gi", "nvidia.com/gpu", "1")),
+		OS:              "linux",
+		NodePressure:    []string{"MemoryPressure"},
+		PodLimits:       ResourceData(quantities("memory", "2Gi")),
+		PodRequests:     ResourceData(quantities("memory", "2Gi")),
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out PodData
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(in, out) {
+		t.Errorf("round trip of %s\ngot  %+v\nwant %+v", data, out, in)
+	}
+	// quantities are written in their canonical form
+	if !strings.Contains(string(data), `"limits":{"cpu":"1500m","memory":"1536Mi"}`) {
+		t.Errorf("json = %s, want canonical quantities", data)
+	}
+}
+
+func TestCollectWorkload(t *testing.T) {
+	guaranteed := quantities("cpu", "1", "memory", "1Gi")
+	template := v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Limits: guaranteed, Requests: guaranteed}}}}}
+	bare := v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "worker"}}}}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}, Spec: appsv1.DeploymentSpec{Template: template}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: appsv1.StatefulSetSpec{Template: template}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "agent"}, Spec: appsv1.DaemonSetSpec{Template: bare}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup"}, Spec: batchv1.JobSpec{Template: bare}})
+	resources := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+	tests := []struct {
+		kind, name string
+		want       string
+		class      PodQosPolicy
+	}{
+		{"deployment", "web", "deployment/web", Guaranteed},
+		{"deploy", "web", "deployment/web", Guaranteed},
+		{"StatefulSet", "db", "statefulset/db", Guaranteed},
+		{"ds", "agent", "daemonset/agent", BestEffort},
+		{"job", "backup", "job/backup", BestEffort},
+	}
+	for _, tt := range tests {
+		podData, err := CollectWorkload(clientset, "default", tt.kind, tt.name)
+		if err != nil {
+			t.Errorf("CollectWorkload(%s/%s) = %v", tt.kind, tt.name, err)
+			continue
+		}
+		if len(podData) != 1 || podData[0].PodName != tt.want || podData[0].QosClass(resources) != tt.class {
+			t.Errorf("CollectWorkload(%s/%s) = %+v, want %s %s", tt.kind, tt.name, podData, tt.want, tt.class)
+		}
+	}
+	if _, err := CollectWorkload(clientset, "default", "cronjob", "nightly"); err == nil {
+		t.Error("CollectWorkload of a cronjob = nil error")
+	}
+	if _, err := CollectWorkload(clientset, "default", "deployment", "missing"); err == nil {
+		t.Error("CollectWorkload of a missing deployment = nil error")
+	}
+}
+
+func TestWorkloadTemplate(t *testing.T) {
+	obj := NewWorkload("STS")
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		t.Fatalf("NewWorkload(STS) = %T, want a StatefulSet", obj)
+	}
+	sts.Namespace, sts.Name = "default", "db"
+	podData, ok := WorkloadTemplate(sts)
+	if !ok || podData.NameSpace != "default" || podData.PodName != "statefulset/db" {
+		t.Errorf("WorkloadTemplate = %s/%s %v, want default/statefulset/db", podData.NameSpace, podData.PodName, ok)
+	}
+	if NewWorkload("cronjob") != nil {
+		t.Error("NewWorkload(cronjob) isn't nil")
+	}
+	if _, ok := WorkloadTemplate(&v1.Pod{}); ok {
+		t.Error("WorkloadTemplate of a pod is ok")
+	}
+}
+
+func TestPodLevelResources(t *testing.T) {
+	resources := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+	pod := v1.Pod{Spec: v1.PodSpec{
+		Resources: &v1.ResourceRequirements{
+			Limits:   quantities("cpu", "2", "memory", "2Gi"),
+			Requests: quantities("cpu", "2", "memory", "2Gi"),
+		},
+		// the containers share the pod's resources without setting any
+		Containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}},
+	}}
+	data := NewPodData(pod)
+	if !data.HasPodLevelResources() {
+		t.Fatal("HasPodLevelResources = false, want true")
+	}
+	if got := data.QosClass(resources); got != Guaranteed {
+		t.Errorf("QosClass = %s, want Guaranteed from the pod-level resources", got)
+	}
+	requests, limits := data.EffectiveResources()
+	if requests.CPU().String() != "2" || limits.Memory().String() != "2Gi" {
+		t.Errorf("effective = %v %v, want the pod-level resources", requests, limits)
+	}
+
+	// pod-level requests below the limits make it Burstable
+	pod.Spec.Resources.Requests = quantities("cpu", "1", "memory", "1Gi")
+	data = NewPodData(pod)
+	if got := data.QosClass(resources); got != Burstable {
+		t.Errorf("QosClass = %s, want Burstable", got)
+	}
+
+	// without them the containers decide, two bare ones are BestEffort
+	pod.Spec.Resources = nil
+	data = NewPodData(pod)
+	if data.HasPodLevelResources() || data.QosClass(resources) != BestEffort {
+		t.Errorf("without pod-level resources QosClass = %s, want BestEffort", data.QosClass(resources))
+	}
+}
+
+func TestPodLevelResourcesOnlyReplaceWhatTheySet(t *testing.T) {
+	pod := v1.Pod{Spec: v1.PodSpec{
+		Resources: &v1.ResourceRequirements{Limits: quantities("memory", "4Gi"), Requests: quantities("memory", "4Gi")},
+		Containers: []v1.Container{
+			{Name: "app", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "1", "memory", "1Gi"), Requests: quantities("cpu", "500m", "memory", "1Gi")}},
+			{Name: "sidecar", Resources: v1.ResourceRequirements{Limits: quantities("cpu", "100m"), Requests: quantities("cpu", "100m")}},
+		},
+	}}
+	data := NewPodData(pod)
+	requests, limits := data.EffectiveResources()
+	if requests.CPU().String() != "600m" || limits.CPU().String() != "1100m" {
+		t.Errorf("effective cpu = %s/%s, want the container sums 600m/1100m", requests.CPU(), limits.CPU())
+	}
+	if requests.Memory().String() != "4Gi" || limits.Memory().String() != "4Gi" {
+		t.Errorf("effective memory = %s/%s, want the pod-level 4Gi", requests.Memory(), limits.Memory())
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyRule requires a minimum class of the pods it matches, by namespace,
+// by pod label selector, or both. An empty rule matches every pod
+type PolicyRule struct {
+	Namespace string              `json:"namespace,omitempty"`
+	Selector  string              `json:"selector,omitempty"`
+	MinClass  podqos.PodQosPolicy `json:"minClass"`
+
+	selector labels.Selector
+}
+
+// AuditPolicy is the file given to --audit-policy, e.g.
+//
+//	rules:
+//	- namespace: prod
+//	  minClass: Guaranteed
+//	- selector: tier=frontend
+//	  minClass: Burstable
+type AuditPolicy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// Violation is a pod whose class is below what a rule requires
+type Violation struct {
+	Context   string
+	Namespace string
+	Pod       string
+	Class     podqos.PodQosPolicy
+	Rule      PolicyRule
+}
+
+// loadPolicy reads and validates the audit policy
+func loadPolicy(path string) (AuditPolicy, error) {
+	var policy AuditPolicy
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse audit policy %s: %v", path, err)
+	}
+	for i, rule := range policy.Rules {
+		if _, ok := classRank[rule.MinClass]; !ok {
+			return policy, fmt.Errorf("audit policy %s: rule %d has unknown minClass %q", path, i+1, rule.MinClass)
+		}
+		if policy.Rules[i].selector, err = labels.Parse(rule.Selector); err != nil {
+			return policy, fmt.Errorf("audit policy %s: rule %d: %v", path, i+1, err)
+		}
+	}
+	return policy, nil
+}
+
+// matches reports whether the rule applies to the pod
+func (r PolicyRule) matches(p podqos.PodData) bool {
+	if r.Namespace != "" && r.Namespace != p.NameSpace {
+		return false
+	}
+	return r.selector == nil || r.selector.Matches(labels.Set(p.Labels))
+}
+
+// String describes the rule in the violations table
+func (r PolicyRule) String() string {
+	var match []string
+	if r.Namespace != "" {
+		match = append(match, "namespace="+r.Namespace)
+	}
+	if r.Selector != "" {
+		match = append(match, r.Selector)
+	}
+	if len(match) == 0 {
+		match = append(match, "all pods")
+	}
+	return strings.Join(match, ",")
+}
+
+// auditPods checks every pod against every rule that matches it, a pod is
+// reported once per rule it violates. The class is the one kubelet gives the
+// pod, a pod Guaranteed on cpu alone is still Burstable to it
+func auditPods(policy AuditPolicy, podData []podqos.PodData) []Violation {
+	var violations []Violation
+	for _, p := range podData {
+		class := p.QosClass(podqos.KubeletResources)
+		for _, rule := range policy.Rules {
+			if rule.matches(p) && classRank[class] < classRank[rule.MinClass] {
+				violations = append(violations, Violation{Context: p.Context, Namespace: p.NameSpace, Pod: p.PodName, Class: class, Rule: rule})
+			}
+		}
+	}
+	return violations
+}
+
+// printViolations writes one row per violation
+func printViolations(w io.Writer, violations []Violation) {
+	if len(violations) == 0 {
+		fmt.Fprintln(w, "no audit policy violations")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"NAMESPACE", "POD NAME", "CLASS", "REQUIRED", "RULE"}, "\t"))
+	for _, v := range violations {
+		fmt.Fprintln(tw, strings.Join([]string{v.Namespace, v.Pod, string(v.Class), string(v.Rule.MinClass), v.Rule.String()}, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePolicy writes the audit policy to a file of the test
+func writePolicy(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuditPods(t *testing.T) {
+	policy, err := loadPolicy(writePolicy(t, `rules:
+- namespace: prod
+  minClass: Guaranteed
+- selector: tier=frontend
+  minClass: Burstable
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontend := newPod("staging", "web", bestEffortContainer("app"))
+	frontend.Labels = map[string]string{"tier": "frontend"}
+	podData := toPodData(
+		newPod("prod", "db", guaranteedContainer("pg")),
+		newPod("prod", "api", burstableContainer("app")),
+		newPod("staging", "batch", bestEffortContainer("job")),
+		frontend)
+
+	violations := auditPods(policy, podData)
+	if len(violations) != 2 {
+		t.Fatalf("violations = %+v, want prod/api and staging/web", violations)
+	}
+	if v := violations[0]; v.Namespace != "prod" || v.Pod != "api" || v.Class != "Burstable" || v.Rule.MinClass != "Guaranteed" {
+		t.Errorf("first violation = %+v, want the Burstable pod in prod", v)
+	}
+	if v := violations[1]; v.Pod != "web" || v.Rule.String() != "tier=frontend" {
+		t.Errorf("second violation = %+v, want the frontend pod", v)
+	}
+
+	var buf bytes.Buffer
+	printViolations(&buf, violations)
+	if got := strings.Join(strings.Fields(strings.Split(buf.String(), "\n")[1]), " "); got != "prod api Burstable Guaranteed namespace=prod" {
+		t.Errorf("first row = %q", got)
+	}
+	buf.Reset()
+	printViolations(&buf, nil)
+	if buf.String() != "no audit policy violations\n" {
+		t.Errorf("without violations printed %q", buf.String())
+	}
+}
+
+func TestAuditPodsUsesTheKubeletClass(t *testing.T) {
+	policy, err := loadPolicy(writePolicy(t, "rules:\n- namespace: prod\n  minClass: Guaranteed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Guaranteed on cpu, the default --resources, but kubelet also looks at
+	// the memory it doesn't request
+	cpu := quantities("cpu", "1")
+	podData := toPodData(newPod("prod", "cache", newContainer("redis", cpu, cpu)))
+	violations := auditPods(policy, podData)
+	if len(violations) != 1 || violations[0].Class != "Burstable" {
+		t.Errorf("violations = %+v, want the cpu-only Guaranteed pod as Burstable", violations)
+	}
+}
+
+func TestLoadPolicyRejects(t *testing.T) {
+	for _, content := range []string{
+		"rules:\n- namespace: prod\n  minClass: Gold\n",
+		"rules:\n- selector: 'tier in (a'\n  minClass: Burstable\n",
+		"rules:\n- namespace: prod\n  minClass: Guaranteed\n  owner: team-a\n",
+	} {
+		if _, err := loadPolicy(writePolicy(t, content)); err == nil {
+			t.Errorf("loadPolicy(%q) = nil error", content)
+		}
+	}
+}
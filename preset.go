@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// presets are named sets of flags --preset turns on, flags given on the
+// command line win over the preset
+var presets = map[string]map[string]string{
+	// triage is for incidents: where pods run, how they restart and how
+	// their usage compares to what they asked for
+	"triage": {
+		"resources":        "cpu,memory",
+		"with-node-status": "true",
+		"show-restarts":    "true",
+		"with-usage":       "true",
+	},
+}
+
+// presetNames lists the presets in name order
+func presetNames() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset sets the flags of the preset that weren't given explicitly
+func applyPreset(flags *flag.FlagSet, name string) error {
+	preset, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown --preset %q, use one of: %s", name, strings.Join(presetNames(), ", "))
+	}
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	var keys []string
+	for key := range preset {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if explicit[key] {
+			continue
+		}
+		if err := flags.Set(key, preset[key]); err != nil {
+			return fmt.Errorf("preset %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestApplyPreset(t *testing.T) {
+	flags := flag.NewFlagSet("podqos", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	resources := flags.String("resources", "", "")
+	nodeStatus := flags.Bool("with-node-status", false, "")
+	restarts := flags.Bool("show-restarts", false, "")
+	usage := flags.Bool("with-usage", false, "")
+	showOS := flags.Bool("show-os", false, "")
+	// a flag given on the command line wins over the preset
+	if err := flags.Parse([]string{"--show-restarts=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyPreset(flags, "triage"); err != nil {
+		t.Fatal(err)
+	}
+	if *resources != "cpu,memory" || !*nodeStatus || !*usage {
+		t.Errorf("triage set resources=%q with-node-status=%v with-usage=%v", *resources, *nodeStatus, *usage)
+	}
+	if *restarts {
+		t.Error("triage overrode the explicit --show-restarts=false")
+	}
+	if *showOS {
+		t.Error("triage set a flag it doesn't list")
+	}
+	if err := applyPreset(flags, "everything"); err == nil {
+		t.Error("applyPreset of an unknown preset = nil error")
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// priorityCell shows the priority class of the pod with the priority it
+// resolved to, e.g. "high (1000)". The number is only there once admission
+// has set it, and pods without a class show "<none>"
+func priorityCell(p podqos.PodData) string {
+	if p.Pod == nil {
+		return "<unknown>"
+	}
+	spec := p.Pod.Spec
+	switch {
+	case spec.PriorityClassName != "" && spec.Priority != nil:
+		return fmt.Sprintf("%s (%d)", spec.PriorityClassName, *spec.Priority)
+	case spec.PriorityClassName != "":
+		return spec.PriorityClassName
+	case spec.Priority != nil && *spec.Priority != 0:
+		return fmt.Sprintf("<none> (%d)", *spec.Priority)
+	}
+	return "<none>"
+}
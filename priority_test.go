@@ -0,0 +1,55 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+func TestPriorityCell(t *testing.T) {
+	priority := func(class string, value *int32) podqos.PodData {
+		pod := newPod("default", "web", guaranteedContainer("app"))
+		pod.Spec.PriorityClassName = class
+		pod.Spec.Priority = value
+		return toPodData(pod)[0]
+	}
+	high, zero, system := int32(1000), int32(0), int32(2000000000)
+	tests := []struct {
+		pod  podqos.PodData
+		want string
+	}{
+		{priority("high", &high), "high (1000)"},
+		{priority("high", nil), "high"},
+		{priority("", &zero), "<none>"},
+		{priority("", nil), "<none>"},
+		{priority("", &system), "<none> (2000000000)"},
+		{podqos.PodData{PodName: "web"}, "<unknown>"},
+	}
+	for _, tt := range tests {
+		if got := priorityCell(tt.pod); got != tt.want {
+			t.Errorf("priorityCell = %q, want %q", got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	printFlat(&buf, []podqos.PodData{priority("high", &high)}, tableOptions{resources: cpuMemory, showPriority: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "PRIORITY") || !strings.HasSuffix(lines[1], "high (1000)") {
+		t.Errorf("table = %q, want a PRIORITY column", lines)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerSpec finds the spec of the app container in the pod the data
+// came from, nil when it isn't known. A duplicate is found by its index
+func containerSpec(p podqos.PodData, name string) *v1.Container {
+	if p.Pod == nil {
+		return nil
+	}
+	if base, index, ok := splitDuplicateName(name); ok {
+		if index < len(p.Pod.Spec.Containers) && p.Pod.Spec.Containers[index].Name == base {
+			return &p.Pod.Spec.Containers[index]
+		}
+		return nil
+	}
+	for i := range p.Pod.Spec.Containers {
+		if p.Pod.Spec.Containers[i].Name == name {
+			return &p.Pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// probeCells say whether the container has a liveness and a readiness
+// probe, a BestEffort container without them is first to be evicted and
+// nothing notices when it hangs
+func probeCells(p podqos.PodData, name string) []string {
+	spec := containerSpec(p, name)
+	if spec == nil {
+		return []string{"<unknown>", "<unknown>"}
+	}
+	return []string{strconv.FormatBool(spec.LivenessProbe != nil), strconv.FormatBool(spec.ReadinessProbe != nil)}
+}
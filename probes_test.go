@@ -0,0 +1,51 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestProbeCells(t *testing.T) {
+	app := bestEffortContainer("app")
+	app.ReadinessProbe = &v1.Probe{ProbeHandler: v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Path: "/ready"}}}
+	proxy := bestEffortContainer("proxy")
+	proxy.LivenessProbe = &v1.Probe{ProbeHandler: v1.ProbeHandler{Exec: &v1.ExecAction{Command: []string{"true"}}}}
+	podData := toPodData(newPod("default", "web", app, proxy))
+
+	for name, want := range map[string]string{"app": "false true", "proxy": "true false", "missing": "<unknown> <unknown>"} {
+		if got := strings.Join(probeCells(podData[0], name), " "); got != want {
+			t.Errorf("%s probes = %q, want %q", name, got, want)
+		}
+	}
+	// pods read from a report have no spec to look at
+	if got := strings.Join(probeCells(podqos.PodData{PodName: "web"}, "app"), " "); got != "<unknown> <unknown>" {
+		t.Errorf("probes without a spec = %q", got)
+	}
+
+	var buf bytes.Buffer
+	printFlat(&buf, podData, tableOptions{resources: cpuMemory, showProbes: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields[len(fields)-2:], " ") != "LIVENESS READINESS" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields[len(fields)-2:], " ") != "false true" {
+		t.Errorf("app row = %q, want a readiness probe but no liveness probe", lines[1])
+	}
+}
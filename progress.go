@@ -0,0 +1,85 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// progress reports on stderr what is being scanned, overwriting a single
+// line. A nil progress prints nothing
+type progress struct {
+	mu   sync.Mutex
+	w    io.Writer
+	done int
+	// paged is set once a page of a single list was reported
+	paged bool
+}
+
+// newProgress returns nil when quiet or when f isn't a terminal, so piped
+// output and CI logs stay clean
+func newProgress(f *os.File, quiet bool) *progress {
+	if quiet || !isTerminal(f) {
+		return nil
+	}
+	return &progress{w: f}
+}
+
+// step records one more of total items, e.g. "scanning context prod (3/10)"
+// when done in order or "scanned namespace prod (3/10)" as concurrent scans
+// finish
+func (p *progress) step(verb, kind, name string, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	// \033[K clears what is left of a longer previous line
+	fmt.Fprintf(p.w, "\r%s %s %s (%d/%d)\033[K", verb, kind, name, p.done, total)
+}
+
+// page reports the pods listed so far as the chunks of a single list of
+// every namespace come in, e.g. "listed 500 pods, about 1200 left", without
+// the estimate when the api server gives none
+func (p *progress) page(listed, remaining int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paged = true
+	if remaining > 0 {
+		fmt.Fprintf(p.w, "\rlisted %d pods, about %d left\033[K", listed, remaining)
+		return
+	}
+	fmt.Fprintf(p.w, "\rlisted %d pods\033[K", listed)
+}
+
+// finish clears the progress line before the results are printed
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done > 0 || p.paged {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+	p.done, p.paged = 0, false
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestProgressSilentWhenPiped(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p := newProgress(f, false)
+	if p != nil {
+		t.Fatal("newProgress of a file isn't nil")
+	}
+	// a nil progress is safe to use
+	p.step("scanned", "namespace", "default", 3)
+	p.finish()
+	if data, _ := os.ReadFile(f.Name()); len(data) != 0 {
+		t.Errorf("progress wrote %q to a non-terminal", data)
+	}
+	if newProgress(os.Stderr, true) != nil {
+		t.Error("newProgress with --quiet isn't nil")
+	}
+}
+
+func TestProgressSteps(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progress{w: &buf}
+	p.step("scanning", "context", "prod", 2)
+	p.step("scanning", "context", "staging", 2)
+	p.finish()
+	want := "\rscanning context prod (1/2)\033[K\rscanning context staging (2/2)\033[K\r\033[K"
+	if buf.String() != want {
+		t.Errorf("progress = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestProgressPagesOfEveryNamespace(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progress{w: &buf}
+	p.page(500, 700)
+	p.page(1000, 0)
+	p.finish()
+	want := "\rlisted 500 pods, about 700 left\033[K\rlisted 1000 pods\033[K\r\033[K"
+	if buf.String() != want {
+		t.Errorf("progress = %q, want %q", buf.String(), want)
+	}
+
+	// collect reports the pages of -A, not of a single namespace
+	clientset := fake.NewSimpleClientset(newPod("default", "web"), newPod("prod", "db"))
+	buf.Reset()
+	if _, err := collect(clientset, "", collectOptions{progress: p}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "\rlisted 2 pods\033[K" {
+		t.Errorf("collect of every namespace wrote %q, want the listed pods", buf.String())
+	}
+	buf.Reset()
+	if _, err := collect(clientset, "default", collectOptions{progress: p}); err != nil || buf.Len() != 0 {
+		t.Errorf("collect of a namespace wrote %q, %v, want nothing", buf.String(), err)
+	}
+}
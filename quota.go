@@ -0,0 +1,114 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// quotaResources are the cpu and memory entries of a ResourceQuota, a bare
+// cpu or memory means the same as requests.cpu or requests.memory
+var quotaResources = []v1.ResourceName{
+	v1.ResourceRequestsCPU,
+	v1.ResourceCPU,
+	v1.ResourceLimitsCPU,
+	v1.ResourceRequestsMemory,
+	v1.ResourceMemory,
+	v1.ResourceLimitsMemory,
+}
+
+// quotaUsage is one cpu or memory entry of a ResourceQuota
+type quotaUsage struct {
+	Namespace string
+	Quota     string
+	Resource  v1.ResourceName
+	Used      resource.Quantity
+	Hard      resource.Quantity
+}
+
+// percent is how much of the hard limit is used, worked out in float64
+// since memory in millibytes times 100 overflows int64 above about 84Ti
+func (q quotaUsage) percent() string {
+	if q.Hard.IsZero() {
+		return "n/a"
+	}
+	used, hard := float64(q.Used.Value()), float64(q.Hard.Value())
+	if quotaKind(q.Resource) == v1.ResourceCPU {
+		used, hard = float64(q.Used.MilliValue()), float64(q.Hard.MilliValue())
+	}
+	return fmt.Sprintf("%d%%", int64(used*100/hard))
+}
+
+// collectQuotas lists the ResourceQuotas of the namespace, an exhausted
+// quota rejects new pods at admission so it decides whether another
+// Guaranteed pod can still be created
+func collectQuotas(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]quotaUsage, error) {
+	quotas, err := clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var usages []quotaUsage
+	for _, quota := range quotas.Items {
+		for _, name := range quotaResources {
+			hard, ok := quota.Status.Hard[name]
+			if !ok {
+				if hard, ok = quota.Spec.Hard[name]; !ok {
+					continue
+				}
+			}
+			usages = append(usages, quotaUsage{
+				Namespace: quota.Namespace,
+				Quota:     quota.Name,
+				Resource:  name,
+				Used:      quota.Status.Used[name],
+				Hard:      hard,
+			})
+		}
+	}
+	return usages, nil
+}
+
+// quotaKind is how a quota entry renders, requests.cpu like cpu and
+// limits.memory like memory
+func quotaKind(name v1.ResourceName) v1.ResourceName {
+	if strings.HasSuffix(string(name), "cpu") {
+		return v1.ResourceCPU
+	}
+	return v1.ResourceMemory
+}
+
+// printQuotas writes one row per quota entry
+func printQuotas(w io.Writer, usages []quotaUsage, style format.Style) {
+	if len(usages) == 0 {
+		fmt.Fprintln(w, "no resource quotas found")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tQUOTA\tRESOURCE\tUSED\tHARD\tUSED%")
+	for _, u := range usages {
+		kind := quotaKind(u.Resource)
+		fmt.Fprintln(tw, strings.Join([]string{u.Namespace, u.Quota, string(u.Resource), format.Resource(style, kind, &u.Used), format.Resource(style, kind, &u.Hard), u.percent()}, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newQuota is a ResourceQuota with its hard limits and current usage
+func newQuota(namespace, name string, hard, used v1.ResourceList) *v1.ResourceQuota {
+	return &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v1.ResourceQuotaSpec{Hard: hard},
+		Status:     v1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func TestCollectQuotas(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newQuota("default", "compute", quantities("requests.cpu", "4", "limits.memory", "8Gi", "pods", "10"), quantities("requests.cpu", "3", "limits.memory", "2Gi", "pods", "4")),
+		newQuota("other", "compute", quantities("cpu", "1"), nil))
+	usages, err := collectQuotas(context.Background(), clientset, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pods isn't cpu or memory, it is left out
+	if len(usages) != 2 {
+		t.Fatalf("collectQuotas = %+v, want requests.cpu and limits.memory", usages)
+	}
+
+	var buf bytes.Buffer
+	printQuotas(&buf, usages, format.Human)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"NAMESPACE QUOTA RESOURCE USED HARD USED%",
+		"default compute requests.cpu 3 4 75%",
+		"default compute limits.memory 2Gi 8Gi 25%",
+	}
+	for i := range want {
+		if got := strings.Join(strings.Fields(lines[i]), " "); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	buf.Reset()
+	printQuotas(&buf, nil, format.Human)
+	if buf.String() != "no resource quotas found\n" {
+		t.Errorf("without quotas printed %q", buf.String())
+	}
+}
+
+func TestQuotaPercent(t *testing.T) {
+	tests := []struct {
+		resource   v1.ResourceName
+		used, hard string
+		want       string
+	}{
+		{v1.ResourceRequestsCPU, "250m", "1", "25%"},
+		{v1.ResourceLimitsCPU, "0", "2", "0%"},
+		{v1.ResourceRequestsMemory, "1Gi", "0", "n/a"},
+		// millibytes of these overflow int64
+		{v1.ResourceRequestsMemory, "100Ti", "200Ti", "50%"},
+		{v1.ResourceLimitsMemory, "1Pi", "1Pi", "100%"},
+	}
+	for _, tt := range tests {
+		u := quotaUsage{Resource: tt.resource, Used: quantities("x", tt.used)["x"], Hard: quantities("x", tt.hard)["x"]}
+		if got := u.percent(); got != tt.want {
+			t.Errorf("percent of %s/%s = %s, want %s", tt.used, tt.hard, got, tt.want)
+		}
+	}
+}
+
+func TestQuotaHeadroom(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newQuota("default", "compute", quantities("requests.cpu", "4", "limits.memory", "8Gi"), quantities("requests.cpu", "3", "limits.memory", "2Gi")))
+	usages, err := collectQuotas(context.Background(), clientset, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for probe, want := range map[string]bool{
+		"cpu=500m,memory=1Gi": true,
+		"cpu=1,memory=6Gi":    true,
+		"cpu=2,memory=1Gi":    false,
+		"cpu=500m,memory=7Gi": false,
+	} {
+		size, err := parseProbeSize(probe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if got := printQuotaHeadroom(&buf, quotaHeadroom(usages, size), format.Human); got != want {
+			t.Errorf("%s fits = %v, want %v\n%s", probe, got, want, buf.String())
+		}
+		answer := "fits: no\n"
+		if want {
+			answer = "fits: yes\n"
+		}
+		if !strings.HasSuffix(buf.String(), answer) {
+			t.Errorf("%s output = %q, want %q", probe, buf.String(), answer)
+		}
+	}
+
+	size, _ := parseProbeSize("cpu=2,memory=1Gi")
+	var buf bytes.Buffer
+	printQuotaHeadroom(&buf, quotaHeadroom(usages, size), format.Human)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{"default compute requests.cpu 2 1 no", "default compute limits.memory 1Gi 6Gi yes"} {
+		if got := strings.Join(strings.Fields(lines[i+1]), " "); got != want {
+			t.Errorf("line %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestParseProbeSize(t *testing.T) {
+	for _, value := range []string{"cpu=500m", "memory=1Gi", "cpu=500m,gpu=1,memory=1Gi", "cpu=lots,memory=1Gi", "cpu"} {
+		if _, err := parseProbeSize(value); err == nil {
+			t.Errorf("parseProbeSize(%q) = nil error", value)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// parseProbeSize parses --probe-size, e.g. cpu=500m,memory=256Mi. A
+// Guaranteed pod needs both
+func parseProbeSize(value string) (podqos.ResourceData, error) {
+	size := podqos.ResourceData{}
+	for _, term := range splitList(value) {
+		parts := strings.SplitN(term, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --probe-size term %q, expected cpu=<quantity> or memory=<quantity>", term)
+		}
+		name := v1.ResourceName(strings.TrimSpace(parts[0]))
+		if name != v1.ResourceCPU && name != v1.ResourceMemory {
+			return nil, fmt.Errorf("invalid --probe-size resource %q, use cpu and memory", name)
+		}
+		q, err := resource.ParseQuantity(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --probe-size %s: %v", name, err)
+		}
+		size[name] = q
+	}
+	if !size.Has(v1.ResourceCPU) || !size.Has(v1.ResourceMemory) {
+		return nil, fmt.Errorf("--probe-size needs both cpu and memory, e.g. cpu=500m,memory=256Mi")
+	}
+	return size, nil
+}
+
+// quotaFit is whether one quota entry has room for the probe pod
+type quotaFit struct {
+	quotaUsage
+	Needed    resource.Quantity
+	Remaining resource.Quantity
+}
+
+// fits reports whether the remaining quota covers what the pod needs
+func (f quotaFit) fits() bool {
+	return f.Remaining.Cmp(f.Needed) >= 0
+}
+
+// quotaHeadroom checks every quota entry against one more Guaranteed pod of
+// the size, its requests and limits are both the size so it counts against
+// the requests and the limits entries alike
+func quotaHeadroom(usages []quotaUsage, size podqos.ResourceData) []quotaFit {
+	var fits []quotaFit
+	for _, u := range usages {
+		remaining := u.Hard.DeepCopy()
+		remaining.Sub(u.Used)
+		fits = append(fits, quotaFit{quotaUsage: u, Needed: *size.Get(quotaKind(u.Resource)), Remaining: remaining})
+	}
+	return fits
+}
+
+// printQuotaHeadroom writes one row per quota entry and then whether the pod
+// fits, which is the case when every entry has room, or there is no quota
+func printQuotaHeadroom(w io.Writer, fits []quotaFit, style format.Style) bool {
+	ok := true
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tQUOTA\tRESOURCE\tNEEDED\tREMAINING\tFITS")
+	for _, f := range fits {
+		kind := quotaKind(f.Resource)
+		answer := "yes"
+		if !f.fits() {
+			answer = "no"
+			ok = false
+		}
+		fmt.Fprintln(tw, strings.Join([]string{f.Namespace, f.Quota, string(f.Resource), format.Resource(style, kind, &f.Needed), format.Resource(style, kind, &f.Remaining), answer}, "\t"))
+	}
+	tw.Flush()
+	if ok {
+		fmt.Fprintln(w, "fits: yes")
+	} else {
+		fmt.Fprintln(w, "fits: no")
+	}
+	return ok
+}
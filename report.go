@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Report is the serialized form of the collected pod data. Every level is a
+// struct so fields always come out in the same order, and the only maps, the
+// limits and requests keyed by resource name, are written with sorted keys by
+// encoding/json. The same pods always give the same bytes, so reports can be
+// committed and diffed
+type Report struct {
+	// APIVersion and Kind are only set when written, see versionedReport
+	APIVersion string      `json:"apiVersion,omitempty"`
+	Kind       string      `json:"kind,omitempty"`
+	Pods       []PodReport `json:"pods"`
+}
+
+// PodReport is a single pod in the report
+type PodReport struct {
+	Context      string            `json:"context,omitempty"`
+	Namespace    string            `json:"namespace"`
+	Name         string            `json:"name"`
+	HPA          string            `json:"hpa,omitempty"`
+	Node         string            `json:"node,omitempty"`
+	NodeStatus   string            `json:"nodeStatus,omitempty"`
+	OS           string            `json:"os,omitempty"`
+	NodePressure []string          `json:"nodePressure,omitempty"`
+	Containers   []ContainerReport `json:"containers"`
+	// class is the class of the pod as a whole, init containers included.
+	// It isn't part of v1alpha1 and is empty in a v1alpha1 report read back
+	class podqos.PodQosPolicy
+}
+
+// ContainerReport is a single container with its computed class, the limits
+// and requests hold every resource set on the container
+type ContainerReport struct {
+	Name     string              `json:"name"`
+	Class    podqos.PodQosPolicy `json:"class"`
+	Limits   podqos.ResourceData `json:"limits"`
+	Requests podqos.ResourceData `json:"requests"`
+}
+
+// newReport converts the collected pod data into its serialized form, the
+// class is computed from the given resources
+func newReport(podData []podqos.PodData, resources []v1.ResourceName) Report {
+	report := Report{Pods: []PodReport{}}
+	for _, p := range podData {
+		pod := PodReport{
+			Context:      p.Context,
+			Namespace:    p.NameSpace,
+			Name:         p.PodName,
+			HPA:          p.HPA,
+			Node:         p.NodeName,
+			NodeStatus:   p.NodeStatus,
+			OS:           p.OS,
+			NodePressure: p.NodePressure,
+			Containers:   []ContainerReport{},
+			class:        p.QosClass(resources),
+		}
+		for _, c := range p.Containers {
+			pod.Containers = append(pod.Containers, ContainerReport{
+				Name:     c.Name,
+				Class:    c.QosClass(resources),
+				Limits:   nonNilResources(c.Limits),
+				Requests: nonNilResources(c.Requests),
+			})
+		}
+		report.Pods = append(report.Pods, pod)
+	}
+	return report
+}
+
+// nonNilResources turns unset resources into an empty object rather than
+// null, so templates and jsonpath can index into them either way
+func nonNilResources(r podqos.ResourceData) podqos.ResourceData {
+	if r == nil {
+		return podqos.ResourceData{}
+	}
+	return r
+}
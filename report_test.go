@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReportBytesAreStable(t *testing.T) {
+	list := quantities("cpu", "1", "memory", "1Gi", "nvidia.com/gpu", "1", "ephemeral-storage", "10Gi", "hugepages-2Mi", "64Mi", "example.com/fpga", "2")
+	fixture := func() Report {
+		return newReport(toPodData(
+			newPod("default", "web", newContainer("app", list, list), burstableContainer("proxy")),
+			newPod("default", "db", guaranteedContainer("pg"))), cpuMemory)
+	}
+	var first bytes.Buffer
+	if err := printJSON(&first, fixture(), false); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		var again bytes.Buffer
+		if err := printJSON(&again, fixture(), false); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first.Bytes(), again.Bytes()) {
+			t.Fatalf("run %d wrote\n%s\nwant\n%s", i, again.String(), first.String())
+		}
+	}
+	// map keys, the extended resources included, come out sorted
+	out := first.String()
+	var last int
+	for _, key := range []string{`"cpu"`, `"ephemeral-storage"`, `"example.com/fpga"`, `"hugepages-2Mi"`, `"memory"`, `"nvidia.com/gpu"`} {
+		i := strings.Index(out[last:], key)
+		if i < 0 {
+			t.Fatalf("%s is missing or out of order in\n%s", key, out)
+		}
+		last += i
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// reportGroup is the api group of the report, the version is picked with
+// --output-version
+const reportGroup = "podqos.jdambly.github.io"
+
+// reportKind is the kind of every versioned report
+const reportKind = "PodQoSReport"
+
+// podReportKind is the kind of a single v1alpha1 pod written on its own
+const podReportKind = "PodQoS"
+
+// outputVersions are the report versions accepted by --output-version.
+// v1alpha1 is the original flat report, v1 groups the node fields and the
+// resources the way kubernetes objects do
+var outputVersions = []string{"v1alpha1", "v1"}
+
+// ReportV1 is the v1 form of the report
+type ReportV1 struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Items      []PodReportV1 `json:"items"`
+}
+
+// PodReportV1 is a single pod, Class is the class of the pod as a whole
+type PodReportV1 struct {
+	Context    string              `json:"context,omitempty"`
+	Namespace  string              `json:"namespace"`
+	Name       string              `json:"name"`
+	Class      podqos.PodQosPolicy `json:"class"`
+	HPA        string              `json:"hpa,omitempty"`
+	Node       *NodeReportV1       `json:"node,omitempty"`
+	Containers []ContainerReportV1 `json:"containers"`
+}
+
+// NodeReportV1 is the node a pod is scheduled on, nil until it is
+type NodeReportV1 struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status,omitempty"`
+	OS       string   `json:"os,omitempty"`
+	Pressure []string `json:"pressure,omitempty"`
+}
+
+// ContainerReportV1 is a single container with its computed class
+type ContainerReportV1 struct {
+	Name      string              `json:"name"`
+	Class     podqos.PodQosPolicy `json:"class"`
+	Resources ResourcesV1         `json:"resources"`
+}
+
+// ResourcesV1 mirrors the resources of a container spec
+type ResourcesV1 struct {
+	Limits   podqos.ResourceData `json:"limits"`
+	Requests podqos.ResourceData `json:"requests"`
+}
+
+// versionedReport stamps the report with its version, or converts it to v1.
+// A single v1alpha1 pod is still written on its own
+func versionedReport(report Report, version string, single bool) interface{} {
+	if version == "v1" {
+		return reportToV1(report)
+	}
+	report.APIVersion = reportGroup + "/v1alpha1"
+	report.Kind = reportKind
+	return serializable(report, single)
+}
+
+// reportToV1 converts the report to v1, the pod class is PodData.QosClass.
+// A v1alpha1 report read back doesn't have it, there it is the class every
+// app container shares, otherwise Burstable
+func reportToV1(report Report) ReportV1 {
+	v1Report := ReportV1{APIVersion: reportGroup + "/v1", Kind: reportKind, Items: []PodReportV1{}}
+	for _, p := range report.Pods {
+		pod := PodReportV1{
+			Context:    p.Context,
+			Namespace:  p.Namespace,
+			Name:       p.Name,
+			Class:      p.class,
+			HPA:        p.HPA,
+			Containers: []ContainerReportV1{},
+		}
+		if p.Node != "" {
+			pod.Node = &NodeReportV1{Name: p.Node, Status: p.NodeStatus, OS: p.OS, Pressure: p.NodePressure}
+		}
+		class := podqos.BestEffort
+		for i, c := range p.Containers {
+			if i == 0 {
+				class = c.Class
+			} else if c.Class != class {
+				class = podqos.Burstable
+			}
+			pod.Containers = append(pod.Containers, ContainerReportV1{
+				Name:      c.Name,
+				Class:     c.Class,
+				Resources: ResourcesV1{Limits: c.Limits, Requests: c.Requests},
+			})
+		}
+		if pod.Class == "" {
+			pod.Class = class
+		}
+		v1Report.Items = append(v1Report.Items, pod)
+	}
+	return v1Report
+}
+
+// reportFromV1 converts a v1 report back, so a v1 baseline can be compared
+// against
+func reportFromV1(v1Report ReportV1) Report {
+	report := Report{Pods: []PodReport{}}
+	for _, p := range v1Report.Items {
+		pod := PodReport{
+			Context:    p.Context,
+			Namespace:  p.Namespace,
+			Name:       p.Name,
+			HPA:        p.HPA,
+			Containers: []ContainerReport{},
+			class:      p.Class,
+		}
+		if p.Node != nil {
+			pod.Node = p.Node.Name
+			pod.NodeStatus = p.Node.Status
+			pod.OS = p.Node.OS
+			pod.NodePressure = p.Node.Pressure
+		}
+		for _, c := range p.Containers {
+			pod.Containers = append(pod.Containers, ContainerReport{
+				Name:     c.Name,
+				Class:    c.Class,
+				Limits:   nonNilResources(c.Resources.Limits),
+				Requests: nonNilResources(c.Resources.Requests),
+			})
+		}
+		report.Pods = append(report.Pods, pod)
+	}
+	return report
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// decode writes v as json and reads it back generically
+func decode(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := printJSON(&buf, v, true); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestOutputVersions(t *testing.T) {
+	report := newReport(toPodData(newPod("default", "web", guaranteedContainer("app"), burstableContainer("proxy"))), cpuMemory)
+
+	alpha := decode(t, versionedReport(report, "v1alpha1", false))
+	if alpha["apiVersion"] != reportGroup+"/v1alpha1" || alpha["kind"] != reportKind {
+		t.Errorf("v1alpha1 is %v %v", alpha["apiVersion"], alpha["kind"])
+	}
+	pod := alpha["pods"].([]interface{})[0].(map[string]interface{})
+	container := pod["containers"].([]interface{})[0].(map[string]interface{})
+	if pod["node"] != "node-1" || container["limits"] == nil || container["resources"] != nil {
+		t.Errorf("v1alpha1 pod = %v, want the flat shape", pod)
+	}
+
+	v1 := decode(t, versionedReport(report, "v1", false))
+	if v1["apiVersion"] != reportGroup+"/v1" || v1["kind"] != reportKind || v1["pods"] != nil {
+		t.Errorf("v1 is %v %v", v1["apiVersion"], v1["kind"])
+	}
+	item := v1["items"].([]interface{})[0].(map[string]interface{})
+	if item["class"] != "Burstable" {
+		t.Errorf("v1 pod class = %v, want Burstable for mixed containers", item["class"])
+	}
+	if node := item["node"].(map[string]interface{}); node["name"] != "node-1" {
+		t.Errorf("v1 node = %v, want it grouped under node", node)
+	}
+	container = item["containers"].([]interface{})[1].(map[string]interface{})
+	resources := container["resources"].(map[string]interface{})
+	if requests := resources["requests"].(map[string]interface{}); requests["cpu"] != "250m" || container["limits"] != nil {
+		t.Errorf("v1 container = %v, want the resources grouped like a container spec", container)
+	}
+}
+
+func TestV1PodClassCountsInitContainers(t *testing.T) {
+	pod := newPod("default", "web", guaranteedContainer("app"))
+	pod.Spec.InitContainers = []v1.Container{bestEffortContainer("setup")}
+	report := newReport(toPodData(pod), cpuMemory)
+	v1Report := reportToV1(report)
+	if got := v1Report.Items[0].Class; got != podqos.Burstable {
+		t.Errorf("v1 pod class = %s, want Burstable for a bare init container", got)
+	}
+	if got := v1Report.Items[0].Containers[0].Class; got != podqos.Guaranteed {
+		t.Errorf("v1 app container class = %s, want Guaranteed", got)
+	}
+	if got := reportToV1(reportFromV1(v1Report)).Items[0].Class; got != podqos.Burstable {
+		t.Errorf("v1 pod class read back = %s, want Burstable", got)
+	}
+}
+
+func TestV1ReadsBackAsBaseline(t *testing.T) {
+	report := newReport(toPodData(newPod("default", "web", burstableContainer("app"))), cpuMemory)
+	var buf bytes.Buffer
+	if err := printJSON(&buf, versionedReport(report, "v1", false), false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "v1.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := loadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := diffReports(baseline, report); len(changes) != 0 {
+		t.Errorf("diff against its own v1 report = %v, want none", changes)
+	}
+}
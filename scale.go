@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// scaleQuantity multiplies the quantity by factor, cpu keeps millicore
+// precision and everything else is rounded to whole units
+func scaleQuantity(name v1.ResourceName, q resource.Quantity, factor float64) resource.Quantity {
+	if name == v1.ResourceCPU {
+		return *resource.NewMilliQuantity(int64(math.Round(float64(q.MilliValue())*factor)), q.Format)
+	}
+	return *resource.NewQuantity(int64(math.Round(float64(q.Value())*factor)), q.Format)
+}
+
+// scaleResources is a copy of r with every quantity multiplied by factor
+func scaleResources(r podqos.ResourceData, factor float64) podqos.ResourceData {
+	if r == nil {
+		return nil
+	}
+	scaled := podqos.ResourceData{}
+	for name, q := range r {
+		scaled[name] = scaleQuantity(name, q, factor)
+	}
+	return scaled
+}
+
+// scaleContainers multiplies the limits, requests and usage of containers
+func scaleContainers(containers []podqos.ContainerData, factor float64) {
+	for i := range containers {
+		containers[i].Limits = scaleResources(containers[i].Limits, factor)
+		containers[i].Requests = scaleResources(containers[i].Requests, factor)
+		containers[i].Usage = scaleResources(containers[i].Usage, factor)
+	}
+}
+
+// scalePods multiplies every quantity of the pods by factor, the values of
+// factor replicas of each pod. Zero stays zero and equal limits and
+// requests stay equal, so the classes don't change
+func scalePods(podData []podqos.PodData, factor float64) {
+	for i := range podData {
+		scaleContainers(podData[i].Containers, factor)
+		scaleContainers(podData[i].InitContainers, factor)
+	}
+}
+
+// printScaleLabel marks the output as a projection
+func printScaleLabel(w io.Writer, factor float64) {
+	fmt.Fprintf(w, "projected at %sx the current requests, limits and usage\n", strconv.FormatFloat(factor, 'g', -1, 64))
+}
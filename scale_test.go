@@ -0,0 +1,94 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestScaleTriplesTotals(t *testing.T) {
+	pods := []*v1.Pod{
+		newPod("default", "web", burstableContainer("app"), bestEffortContainer("sidecar")),
+		newPod("default", "db", guaranteedContainer("pg")),
+	}
+	before, err := ownerTotals(context.Background(), toPodData(pods...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	podData := toPodData(pods...)
+	scalePods(podData, 3)
+	after, err := ownerTotals(context.Background(), podData, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("totals = %v, want %v", after, before)
+	}
+	for i := range before {
+		for kind, sums := range map[string][2]podqos.ResourceData{
+			"limits":   {before[i].Limits, after[i].Limits},
+			"requests": {before[i].Requests, after[i].Requests},
+		} {
+			for _, name := range cpuMemory {
+				want := sums[0][name]
+				want.Mul(3)
+				if got := sums[1][name]; got.Cmp(want) != 0 {
+					t.Errorf("%s %s %s = %s, want %s", before[i].Name, kind, name, got.String(), want.String())
+				}
+			}
+		}
+	}
+	// the classes are the same at any scale
+	for i, p := range toPodData(pods...) {
+		for j, c := range p.Containers {
+			if got, want := podData[i].Containers[j].QosClass(cpuMemory), c.QosClass(cpuMemory); got != want {
+				t.Errorf("%s/%s scaled class = %s, want %s", p.PodName, c.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestScaleQuantity(t *testing.T) {
+	for _, test := range []struct {
+		name     v1.ResourceName
+		quantity string
+		factor   float64
+		want     string
+	}{
+		{v1.ResourceCPU, "250m", 1.5, "375m"},
+		{v1.ResourceCPU, "1", 3, "3"},
+		{v1.ResourceCPU, "1m", 0.4, "0"},
+		{v1.ResourceMemory, "128Mi", 1.5, "192Mi"},
+		{v1.ResourceMemory, "1Gi", 3, "3Gi"},
+	} {
+		got := scaleQuantity(test.name, resource.MustParse(test.quantity), test.factor)
+		if want := resource.MustParse(test.want); got.Cmp(want) != 0 {
+			t.Errorf("%s %s x %v = %s, want %s", test.name, test.quantity, test.factor, got.String(), test.want)
+		}
+	}
+}
+
+func TestPrintScaleLabel(t *testing.T) {
+	var buf bytes.Buffer
+	printScaleLabel(&buf, 2.5)
+	if want := "projected at 2.5x the current requests, limits and usage\n"; buf.String() != want {
+		t.Errorf("label = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newPodQosHandler serves GET /podqos?namespace=<ns>&format=json, an empty
+// or missing namespace lists every namespace. client is asked for the
+// client on every request
+func newPodQosHandler(client func() (kubernetes.Interface, error), resources []v1.ResourceName) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/podqos", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if f := r.URL.Query().Get("format"); f != "" && f != "json" {
+			http.Error(w, fmt.Sprintf("unsupported format %q", f), http.StatusBadRequest)
+			return
+		}
+		clientset, err := client()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		podData, err := podqos.CollectPodData(clientset, r.URL.Query().Get("namespace"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newReport(podData, resources))
+	})
+	return mux
+}
+
+// cachedClient builds the client once and hands the same one to every
+// request, reload swaps in a new one built from the current kubeconfig
+type cachedClient struct {
+	newClient func() (kubernetes.Interface, error)
+
+	mu     sync.RWMutex
+	client kubernetes.Interface
+}
+
+func newCachedClient(newClient func() (kubernetes.Interface, error)) (*cachedClient, error) {
+	c := &cachedClient{newClient: newClient}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// get returns the current client
+func (c *cachedClient) get() (kubernetes.Interface, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client, nil
+}
+
+// reload builds a new client, the old one is kept when that fails
+func (c *cachedClient) reload() error {
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	return nil
+}
+
+// serve runs the http server until SIGINT, then gives in flight requests a
+// few seconds to finish. The client is built once up front and rebuilt on
+// SIGHUP, e.g. after the kubeconfig was rotated
+func serve(addr string, newClient func() (kubernetes.Interface, error), resources []v1.ResourceName) error {
+	client, err := newCachedClient(newClient)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: newPodQosHandler(client.get, resources)}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	logger.infof("serving on %s", addr)
+
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case <-hup:
+			if err := client.reload(); err != nil {
+				logger.warnf("failed to reload the kubeconfig, keeping the current client: %v", err)
+				continue
+			}
+			logger.infof("reloaded the kubeconfig")
+		case <-stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(ctx)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodQosHandler(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newPod("default", "web", burstableContainer("app")),
+		newPod("other", "db", guaranteedContainer("pg")))
+	client := func() (kubernetes.Interface, error) { return clientset, nil }
+	server := httptest.NewServer(newPodQosHandler(client, cpuMemory))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/podqos?namespace=default&format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pods) != 1 || report.Pods[0].Name != "web" {
+		t.Fatalf("pods = %+v, want web only", report.Pods)
+	}
+	if c := report.Pods[0].Containers[0]; c.Name != "app" || c.Class != podqos.Burstable || c.Requests.CPU().String() != "250m" {
+		t.Errorf("container = %+v, want app Burstable requesting 250m", c)
+	}
+
+	// without a namespace every namespace is listed
+	resp, err = http.Get(server.URL + "/podqos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	report = Report{}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pods) != 2 {
+		t.Errorf("pods = %+v, want both namespaces", report.Pods)
+	}
+}
+
+func TestPodQosHandlerRejects(t *testing.T) {
+	client := func() (kubernetes.Interface, error) { return fake.NewSimpleClientset(), nil }
+	server := httptest.NewServer(newPodQosHandler(client, cpuMemory))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/podqos?format=yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("format=yaml status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	resp, err = http.Post(server.URL+"/podqos", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// podCount gets /podqos and returns the number of pods in the report
+func podCount(t *testing.T, url string) int {
+	t.Helper()
+	resp, err := http.Get(url + "/podqos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return len(report.Pods)
+}
+
+func TestServerReusesClient(t *testing.T) {
+	var builds int64
+	clientsets := []kubernetes.Interface{
+		fake.NewSimpleClientset(newPod("default", "web", burstableContainer("app"))),
+		fake.NewSimpleClientset(newPod("default", "web", burstableContainer("app")), newPod("default", "db", guaranteedContainer("pg"))),
+	}
+	var failReload bool
+	newClient := func() (kubernetes.Interface, error) {
+		if failReload {
+			return nil, errors.New("kubeconfig is gone")
+		}
+		n := atomic.AddInt64(&builds, 1)
+		return clientsets[n-1], nil
+	}
+	client, err := newCachedClient(newClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newPodQosHandler(client.get, cpuMemory))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n := podCount(t, server.URL); n != 1 {
+				t.Errorf("pods = %d, want 1", n)
+			}
+		}()
+	}
+	wg.Wait()
+	if builds != 1 {
+		t.Errorf("the client was built %d times for 5 requests, want once", builds)
+	}
+
+	// SIGHUP builds a new client, the requests after it use that one
+	if err := client.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if n := podCount(t, server.URL); n != 2 || builds != 2 {
+		t.Errorf("after reload pods = %d with %d builds, want 2 and 2", n, builds)
+	}
+	failReload = true
+	if err := client.reload(); err == nil {
+		t.Error("reload = nil error, want the build error")
+	}
+	if n := podCount(t, server.URL); n != 2 {
+		t.Errorf("after a failed reload pods = %d, want the last client kept", n)
+	}
+}
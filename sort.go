@@ -0,0 +1,203 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"sort"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// sortKeys are the values accepted by --sort-by
+var sortKeys = []string{"effective-cpu", "effective-memory", "eviction"}
+
+// containerSortKeys are the values accepted by --container-sort
+var containerSortKeys = []string{"name", "cpu", "memory"}
+
+// validSortKey reports whether key is empty or one of keys
+func validSortKey(key string, keys []string) bool {
+	if key == "" {
+		return true
+	}
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sortPods orders the pods by the sort key, biggest first, or most likely
+// to be evicted first for the eviction key. The effective keys rank pods by
+// their scheduling footprint, so a pod with a large init container ranks
+// above one whose app containers only add up to more on paper
+func sortPods(podData []podqos.PodData, key string, resources []v1.ResourceName) {
+	var name v1.ResourceName
+	switch key {
+	case "eviction":
+		sortByEviction(podData, resources)
+		return
+	case "effective-cpu":
+		name = v1.ResourceCPU
+	case "effective-memory":
+		name = v1.ResourceMemory
+	default:
+		return
+	}
+	requests := make([]podqos.ResourceData, len(podData))
+	for i := range podData {
+		requests[i], _ = podData[i].EffectiveResources()
+	}
+	sort.Stable(byRequest{podData: podData, requests: requests, name: name})
+}
+
+// topPods keeps the first n pods of the sorted pods, zero keeps them all
+func topPods(podData []podqos.PodData, n int) []podqos.PodData {
+	if n > 0 && len(podData) > n {
+		return podData[:n]
+	}
+	return podData
+}
+
+// byRequest sorts pods by a precomputed request, biggest first
+type byRequest struct {
+	podData  []podqos.PodData
+	requests []podqos.ResourceData
+	name     v1.ResourceName
+}
+
+func (b byRequest) Len() int { return len(b.podData) }
+
+func (b byRequest) Less(i, j int) bool {
+	return b.requests[i].Get(b.name).Cmp(*b.requests[j].Get(b.name)) > 0
+}
+
+func (b byRequest) Swap(i, j int) {
+	b.podData[i], b.podData[j] = b.podData[j], b.podData[i]
+	b.requests[i], b.requests[j] = b.requests[j], b.requests[i]
+}
+
+// classRank orders the classes from least to most protected, the lowest
+// is evicted first
+var classRank = map[podqos.PodQosPolicy]int{
+	podqos.BestEffort: 0,
+	podqos.Burstable:  1,
+	podqos.Guaranteed: 2,
+}
+
+// burstHeadroom is how much memory the pod may use above its requests, the
+// sum of limit minus request over its containers. unbounded is set when a
+// container requests memory without a limit
+func burstHeadroom(p podqos.PodData) (headroom int64, unbounded bool) {
+	for _, c := range p.Containers {
+		if !c.Limits.Has(v1.ResourceMemory) {
+			unbounded = true
+			continue
+		}
+		headroom += c.Limits.Memory().Value() - c.Requests.Memory().Value()
+	}
+	return headroom, unbounded
+}
+
+// sortByEviction orders the pods the way kubelet ranks them for eviction
+// under memory pressure, without looking at actual usage:
+//  1. BestEffort pods come first, they have no requests to stay within
+//  2. Burstable pods next, those that could exceed their requests the most
+//     first, a container without a memory limit counts as unbounded
+//  3. Guaranteed pods come last
+//
+// Pods that rank the same keep their order
+func sortByEviction(podData []podqos.PodData, resources []v1.ResourceName) {
+	type rank struct {
+		order     int
+		unbounded bool
+		headroom  int64
+	}
+	ranks := make([]rank, len(podData))
+	for i := range podData {
+		ranks[i].order = classRank[podData[i].QosClass(resources)]
+		ranks[i].headroom, ranks[i].unbounded = burstHeadroom(podData[i])
+	}
+	indexes := make([]int, len(podData))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := ranks[indexes[i]], ranks[indexes[j]]
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		if a.unbounded != b.unbounded {
+			return a.unbounded
+		}
+		return a.headroom > b.headroom
+	})
+	sorted := make([]podqos.PodData, len(podData))
+	for i, index := range indexes {
+		sorted[i] = podData[index]
+	}
+	copy(podData, sorted)
+}
+
+// sortContainers orders the containers of every pod by name, or by their
+// cpu or memory request biggest first, so multi-container pods always list
+// them the same way
+func sortContainers(podData []podqos.PodData, key string) {
+	for i := range podData {
+		// copy so the pod's own slice keeps its spec order
+		containers := append([]podqos.ContainerData(nil), podData[i].Containers...)
+		switch key {
+		case "name":
+			sort.SliceStable(containers, func(a, b int) bool {
+				return containers[a].Name < containers[b].Name
+			})
+		case "cpu", "memory":
+			name := v1.ResourceName(key)
+			sort.SliceStable(containers, func(a, b int) bool {
+				return containers[a].Requests.Get(name).Cmp(*containers[b].Requests.Get(name)) > 0
+			})
+		default:
+			return
+		}
+		podData[i].Containers = containers
+	}
+}
+
+// podUID is the uid of the pod the data came from, empty when unknown
+func podUID(p podqos.PodData) string {
+	if p.Pod == nil {
+		return ""
+	}
+	return string(p.Pod.UID)
+}
+
+// sortByIdentity orders the pods by context, namespace, name and finally
+// uid. The other sorts are stable, so running this first breaks their ties
+// the same way on every run, even for pods of the same name
+func sortByIdentity(podData []podqos.PodData) {
+	sort.SliceStable(podData, func(i, j int) bool {
+		a, b := podData[i], podData[j]
+		if a.Context != b.Context {
+			return a.Context < b.Context
+		}
+		if a.NameSpace != b.NameSpace {
+			return a.NameSpace < b.NameSpace
+		}
+		if a.PodName != b.PodName {
+			return a.PodName < b.PodName
+		}
+		return podUID(a) < podUID(b)
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podNames is the pod names in order
+func podNames(podData []podqos.PodData) string {
+	var names []string
+	for _, p := range podData {
+		names = append(names, p.PodName)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestSortByEffectiveCPU(t *testing.T) {
+	// the app containers of wide add up to 1500m, migrate runs a 2 cpu init
+	// container before its 500m app
+	wide := newPod("default", "wide", newContainer("a", nil, quantities("cpu", "1")), newContainer("b", nil, quantities("cpu", "500m")))
+	migrate := newPod("default", "migrate", newContainer("app", nil, quantities("cpu", "500m")))
+	migrate.Spec.InitContainers = []v1.Container{newContainer("migrate", nil, quantities("cpu", "2"))}
+	small := newPod("default", "small", newContainer("app", nil, quantities("cpu", "100m")))
+
+	podData := toPodData(small, wide, migrate)
+	sortPods(podData, "effective-cpu", cpuMemory)
+	if got := podNames(podData); got != "migrate wide small" {
+		t.Errorf("effective-cpu order = %q, want the init container to rank migrate first", got)
+	}
+}
+
+func TestSortByEffectiveMemory(t *testing.T) {
+	big := newPod("default", "big", newContainer("app", nil, quantities("memory", "4Gi")))
+	none := newPod("default", "none", bestEffortContainer("app"))
+	mid := newPod("default", "mid", newContainer("app", nil, quantities("memory", "512Mi")))
+
+	podData := toPodData(none, mid, big)
+	sortPods(podData, "effective-memory", cpuMemory)
+	if got := podNames(podData); got != "big mid none" {
+		t.Errorf("effective-memory order = %q", got)
+	}
+	// an unknown or empty key keeps the order
+	sortPods(podData, "", cpuMemory)
+	if got := podNames(podData); got != "big mid none" {
+		t.Errorf("order without a key = %q", got)
+	}
+}
+
+func TestTopPods(t *testing.T) {
+	var pods []*v1.Pod
+	for name, cpu := range map[string]string{"a": "100m", "b": "2", "c": "500m", "d": "1", "e": "250m"} {
+		pods = append(pods, newPod("default", name, newContainer("app", nil, quantities("cpu", cpu))))
+	}
+	podData := toPodData(pods...)
+	sortPods(podData, "effective-cpu", cpuMemory)
+	if got := podNames(topPods(podData, 3)); got != "b d c" {
+		t.Errorf("top 3 = %q, want the three biggest cpu requests", got)
+	}
+	for _, n := range []int{0, 5, 10} {
+		if got := topPods(podData, n); len(got) != 5 {
+			t.Errorf("top %d kept %d pods, want all 5", n, len(got))
+		}
+	}
+
+	// only the top rows are rendered
+	server := newAPIServer(t, pods...)
+	out := runPodqos(t, "--server", server.URL, "-n", "default", "--sort-by", "effective-cpu", "--top", "2")
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	if got := strings.Join(rows, "\n"); got != "default b app <none> 2 Burstable\ndefault d app <none> 1 Burstable" {
+		t.Errorf("--top 2 rows = %q, want b and d only", rows)
+	}
+}
+
+func TestSortByEviction(t *testing.T) {
+	guaranteed := newPod("default", "guaranteed", guaranteedContainer("app"))
+	// 1Gi limit over a 128Mi request
+	burstable := newPod("default", "burstable", burstableContainer("app"))
+	// 512Mi limit over a 256Mi request
+	tight := newPod("default", "tight", newContainer("app", quantities("cpu", "1", "memory", "512Mi"), quantities("cpu", "1", "memory", "256Mi")))
+	// no memory limit at all
+	unbounded := newPod("default", "unbounded", newContainer("app", quantities("cpu", "1"), quantities("cpu", "500m", "memory", "64Mi")))
+	bestEffort := newPod("default", "besteffort", bestEffortContainer("app"))
+
+	podData := toPodData(guaranteed, tight, burstable, unbounded, bestEffort)
+	sortPods(podData, "eviction", cpuMemory)
+	if got := podNames(podData); got != "besteffort unbounded burstable tight guaranteed" {
+		t.Errorf("eviction order = %q", got)
+	}
+}
+
+func TestSortContainers(t *testing.T) {
+	pod := newPod("default", "web",
+		newContainer("proxy", nil, quantities("cpu", "1", "memory", "64Mi")),
+		newContainer("app", nil, quantities("cpu", "2", "memory", "128Mi")),
+		newContainer("metrics", nil, quantities("cpu", "500m", "memory", "1Gi")))
+	for key, want := range map[string]string{
+		"name":   "app metrics proxy",
+		"cpu":    "app proxy metrics",
+		"memory": "metrics app proxy",
+		// an unknown key keeps the spec order
+		"none": "proxy app metrics",
+	} {
+		podData := toPodData(pod)
+		sortContainers(podData, key)
+		var got []string
+		for _, c := range podData[0].Containers {
+			got = append(got, c.Name)
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("sorted by %s = %v, want %s", key, got, want)
+		}
+	}
+}
+
+func TestSortByIdentity(t *testing.T) {
+	web := func(namespace, uid string) *v1.Pod {
+		pod := newPod(namespace, "web", guaranteedContainer("app"))
+		pod.UID = types.UID(uid)
+		return pod
+	}
+	// the same pods listed in every order sort the same way
+	orders := [][]*v1.Pod{
+		{web("shop", "uid-2"), web("blog", "uid-3"), web("shop", "uid-1")},
+		{web("shop", "uid-1"), web("shop", "uid-2"), web("blog", "uid-3")},
+		{web("blog", "uid-3"), web("shop", "uid-1"), web("shop", "uid-2")},
+	}
+	for _, pods := range orders {
+		podData := toPodData(pods...)
+		sortByIdentity(podData)
+		sortPods(podData, "effective-cpu", cpuMemory)
+		var got []string
+		for _, p := range podData {
+			got = append(got, p.NameSpace+"/"+p.PodName+"/"+podUID(p))
+		}
+		if want := "blog/web/uid-3 shop/web/uid-1 shop/web/uid-2"; strings.Join(got, " ") != want {
+			t.Errorf("sorted %v, want %s", got, want)
+		}
+	}
+}
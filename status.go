@@ -0,0 +1,108 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerStatus finds the status of the container in the pod the data
+// came from, nil when it isn't known or the container hasn't started. The
+// statuses of duplicates can only be told apart by order, the nth container
+// of a name gets the nth status of that name
+func containerStatus(p podqos.PodData, name string) *v1.ContainerStatus {
+	if p.Pod == nil {
+		return nil
+	}
+	base, index, duplicate := splitDuplicateName(name)
+	for _, kind := range []struct {
+		specs    []v1.Container
+		statuses []v1.ContainerStatus
+	}{
+		{p.Pod.Spec.Containers, p.Pod.Status.ContainerStatuses},
+		{p.Pod.Spec.InitContainers, p.Pod.Status.InitContainerStatuses},
+	} {
+		occurrence := 0
+		if duplicate {
+			if index >= len(kind.specs) || kind.specs[index].Name != base {
+				continue
+			}
+			for _, spec := range kind.specs[:index] {
+				if spec.Name == base {
+					occurrence++
+				}
+			}
+		}
+		for i := range kind.statuses {
+			if kind.statuses[i].Name != base {
+				continue
+			}
+			if occurrence == 0 {
+				return &kind.statuses[i]
+			}
+			occurrence--
+		}
+	}
+	return nil
+}
+
+// restartsCell is how often the container restarted, a Burstable container
+// going over its memory limit shows up here as OOM kills
+func restartsCell(p podqos.PodData, name string) string {
+	status := containerStatus(p, name)
+	if status == nil {
+		return "<none>"
+	}
+	return strconv.Itoa(int(status.RestartCount))
+}
+
+// statusClass is the class kubelet reported in the status of the pod the
+// data came from, empty when it isn't known, e.g. for manifests
+func statusClass(p podqos.PodData) podqos.PodQosPolicy {
+	if p.Pod == nil {
+		return ""
+	}
+	return podqos.PodQosPolicy(p.Pod.Status.QOSClass)
+}
+
+// statusClassCells are the computed class of the pod and the one in its
+// status, the class column is per container while the status only has one
+// for the whole pod. Both come from cpu and memory, like kubelet computes it,
+// whatever --resources shows
+func statusClassCells(p podqos.PodData) []string {
+	reported := string(statusClass(p))
+	if reported == "" {
+		reported = "<unknown>"
+	}
+	return []string{string(p.QosClass(podqos.KubeletResources)), reported}
+}
+
+// filterMismatches keeps the pods whose computed class differs from the
+// class in their status, pods without a status class are left out. The class
+// is computed from cpu and memory the way kubelet does, so only pods kubelet
+// classes differently, e.g. with a stale status or an annotated class, are
+// kept
+func filterMismatches(podData []podqos.PodData) []podqos.PodData {
+	var kept []podqos.PodData
+	for _, p := range podData {
+		if reported := statusClass(p); reported != "" && reported != p.QosClass(podqos.KubeletResources) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
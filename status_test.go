@@ -0,0 +1,66 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterMismatches(t *testing.T) {
+	matching := newPod("default", "matching", guaranteedContainer("app"))
+	matching.Status.QOSClass = v1.PodQOSGuaranteed
+	// the status says Guaranteed but the sidecar only requests
+	mismatched := newPod("default", "mismatched", guaranteedContainer("app"), burstableContainer("sidecar"))
+	mismatched.Status.QOSClass = v1.PodQOSGuaranteed
+	// a Burstable init container makes the whole pod Burstable, as kubelet says
+	withInit := newPod("default", "with-init", guaranteedContainer("app"))
+	withInit.Spec.InitContainers = []v1.Container{burstableContainer("init")}
+	withInit.Status.QOSClass = v1.PodQOSBurstable
+	unknown := newPod("default", "unknown", bestEffortContainer("app"))
+	// Guaranteed on cpu alone, kubelet also looks at memory and says Burstable
+	cpu := quantities("cpu", "1")
+	cpuOnly := newPod("default", "cpu-only", newContainer("app", cpu, cpu))
+	cpuOnly.Status.QOSClass = v1.PodQOSBurstable
+
+	kept := filterMismatches(toPodData(matching, mismatched, withInit, unknown, cpuOnly))
+	if len(kept) != 1 || kept[0].PodName != "mismatched" {
+		t.Fatalf("filterMismatches kept %v, want only mismatched", names(kept))
+	}
+	if got := statusClassCells(kept[0]); got[0] != "Burstable" || got[1] != "Guaranteed" {
+		t.Errorf("statusClassCells = %v, want [Burstable Guaranteed]", got)
+	}
+	if got := statusClassCells(toPodData(unknown)[0]); got[1] != "<unknown>" {
+		t.Errorf("statusClassCells without a status class = %v, want <unknown>", got)
+	}
+}
+
+func TestOnlyMismatchesFlag(t *testing.T) {
+	matching := newPod("default", "matching", guaranteedContainer("app"))
+	matching.Status.QOSClass = v1.PodQOSGuaranteed
+	mismatched := newPod("default", "mismatched", guaranteedContainer("app"), burstableContainer("sidecar"))
+	mismatched.Status.QOSClass = v1.PodQOSGuaranteed
+	cpu := quantities("cpu", "1")
+	cpuOnly := newPod("default", "cpu-only", newContainer("app", cpu, cpu))
+	cpuOnly.Status.QOSClass = v1.PodQOSBurstable
+	server := newAPIServer(t, matching, mismatched, cpuOnly)
+
+	// the default --resources of cpu alone doesn't make cpu-only a mismatch
+	out := runPodqos(t, "--server", server.URL, "-n", "default", "--only-mismatches")
+	if strings.Contains(out, "matching") || strings.Contains(out, "cpu-only") || !strings.Contains(out, "mismatched") {
+		t.Errorf("--only-mismatches = %q, want only mismatched", out)
+	}
+}
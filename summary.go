@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// NamespaceSummary holds the aggregated counts for a single namespace
+type NamespaceSummary struct {
+	Context   string
+	NameSpace string
+	Pods      int
+	// Containers counts app and init containers, sidecar heavy namespaces
+	// have far more containers than pods
+	Containers int
+}
+
+// summarize aggregates the pod data per context and namespace, keeping the
+// order in which each namespace was first seen
+func summarize(podData []podqos.PodData) []NamespaceSummary {
+	var summaries []NamespaceSummary
+	index := map[string]int{}
+	for _, pod := range podData {
+		key := pod.Context + "/" + pod.NameSpace
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, NamespaceSummary{Context: pod.Context, NameSpace: pod.NameSpace})
+		}
+		summaries[i].Pods++
+		summaries[i].Containers += len(pod.Containers) + len(pod.InitContainers)
+	}
+	return summaries
+}
+
+// printSummary writes one row per namespace, prefixed with the context when
+// showContext is set
+func printSummary(w io.Writer, summaries []NamespaceSummary, showContext bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAMESPACE", "PODS", "CONTAINERS"}
+	if showContext {
+		header = append([]string{"CONTEXT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, s := range summaries {
+		row := []string{s.NameSpace, strconv.Itoa(s.Pods), strconv.Itoa(s.Containers)}
+		if showContext {
+			row = append([]string{s.Context}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// classCounts tallies the pods per class
+func classCounts(podData []podqos.PodData, resources []v1.ResourceName) map[podqos.PodQosPolicy]int {
+	counts := map[podqos.PodQosPolicy]int{}
+	for i := range podData {
+		counts[podData[i].QosClass(resources)]++
+	}
+	return counts
+}
+
+// printSummaryFooter writes a one line tally of the pods per class, e.g.
+// "# 12 pods: 3 Guaranteed, 7 Burstable, 2 BestEffort"
+func printSummaryFooter(w io.Writer, podData []podqos.PodData, resources []v1.ResourceName) {
+	counts := classCounts(podData, resources)
+	fmt.Fprintf(w, "# %d pods: %d %s, %d %s, %d %s\n", len(podData),
+		counts[podqos.Guaranteed], podqos.Guaranteed,
+		counts[podqos.Burstable], podqos.Burstable,
+		counts[podqos.BestEffort], podqos.BestEffort)
+}
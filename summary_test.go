@@ -0,0 +1,90 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeCountsContainers(t *testing.T) {
+	web := newPod("default", "web", burstableContainer("app"), bestEffortContainer("proxy"), bestEffortContainer("logs"))
+	web.Spec.InitContainers = append(web.Spec.InitContainers, bestEffortContainer("migrate"))
+	podData := toPodData(web, newPod("default", "db", guaranteedContainer("pg")), newPod("kube-system", "dns", bestEffortContainer("coredns")))
+
+	summaries := summarize(podData)
+	want := []NamespaceSummary{
+		{NameSpace: "default", Pods: 2, Containers: 5},
+		{NameSpace: "kube-system", Pods: 1, Containers: 1},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("summarize = %+v, want %+v", summaries, want)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("summary %d = %+v, want %+v", i, summaries[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	printSummary(&buf, summaries, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "NAMESPACE PODS CONTAINERS" {
+		t.Errorf("header = %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "default 2 5" {
+		t.Errorf("default row = %q, want 2 pods with 5 containers", got)
+	}
+}
+
+func TestSummarizeKeepsContextsApart(t *testing.T) {
+	podData := toPodData(newPod("default", "web", bestEffortContainer("app")), newPod("default", "web", bestEffortContainer("app")))
+	podData[0].Context, podData[1].Context = "prod", "staging"
+
+	summaries := summarize(podData)
+	if len(summaries) != 2 {
+		t.Fatalf("summarize = %+v, want a row per context", summaries)
+	}
+	var buf bytes.Buffer
+	printSummary(&buf, summaries, true)
+	if got := strings.Join(strings.Fields(strings.Split(buf.String(), "\n")[2]), " "); got != "staging default 1 1" {
+		t.Errorf("staging row = %q", got)
+	}
+}
+
+func TestPrintSummaryFooter(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "db", guaranteedContainer("pg")),
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "batch", bestEffortContainer("job")),
+		newPod("default", "cron", bestEffortContainer("job")))
+	opts := tableOptions{resources: cpuMemory}
+	var buf bytes.Buffer
+	printTable(&buf, podData, opts)
+	printSummaryFooter(&buf, podData, cpuMemory)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got, want := lines[len(lines)-1], "# 4 pods: 1 Guaranteed, 1 Burstable, 2 BestEffort"; got != want {
+		t.Errorf("footer = %q, want %q", got, want)
+	}
+	// the tally matches the class column of the rows above it
+	counts := map[string]int{}
+	for _, line := range lines[1 : len(lines)-1] {
+		fields := strings.Fields(line)
+		counts[fields[len(fields)-1]]++
+	}
+	if counts["Guaranteed"] != 1 || counts["Burstable"] != 1 || counts["BestEffort"] != 2 {
+		t.Errorf("rows have classes %v", counts)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/jdambly/kubectl-podqos/internal/format"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// goTemplatePrefix starts a -o go-template=<template> value
+const goTemplatePrefix = "go-template="
+
+// templateFuncs are the functions available to -o go-template on top of the
+// text/template builtins:
+//
+//	quantity   renders a quantity the way the table does, e.g. {{quantity "memory" .limits.memory}} gives 1.5Gi
+//	millicores converts a cpu quantity to millicores, e.g. {{millicores .requests.cpu}} gives 250
+//	upper      upper cases a string
+//	lower      lower cases a string
+//	default    gives the first argument when the second is empty or missing, e.g. {{default "none" .hpa}}
+var templateFuncs = template.FuncMap{
+	"quantity":   templateQuantity,
+	"millicores": templateMillicores,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"default":    templateDefault,
+}
+
+// templateQuantity renders value, a quantity string from the report, in
+// the human style of the resource. Missing values render as <none>
+func templateQuantity(name string, value interface{}) (string, error) {
+	if value == nil {
+		return "<none>", nil
+	}
+	q, err := resource.ParseQuantity(fmt.Sprint(value))
+	if err != nil {
+		return "", err
+	}
+	return format.Resource(format.Human, v1.ResourceName(name), &q), nil
+}
+
+// templateMillicores converts value, a cpu quantity string from the report,
+// to millicores. Missing values are 0
+func templateMillicores(value interface{}) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(fmt.Sprint(value))
+	if err != nil {
+		return 0, err
+	}
+	return q.MilliValue(), nil
+}
+
+// templateDefault returns value unless it is missing or the zero value of
+// its type, then fallback
+func templateDefault(fallback, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	if v := reflect.ValueOf(value); v.IsZero() || (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return fallback
+	}
+	return value
+}
+
+// parseGoTemplate parses the template of -o go-template=. A missing field
+// prints <no value> like it does with kubectl, passed to default or another
+// function it is nil so default can replace it
+func parseGoTemplate(text string) (*template.Template, error) {
+	t, err := template.New("podqos").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %v", err)
+	}
+	return t, nil
+}
+
+// printGoTemplate executes the template over the json form of v, so the
+// field names are the same as in -o json
+func printGoTemplate(w io.Writer, t *template.Template, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	return t.Execute(w, obj)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGoTemplateFuncs(t *testing.T) {
+	report := newReport(toPodData(newPod("default", "web",
+		newContainer("app", quantities("cpu", "1", "memory", "1536Mi"), quantities("cpu", "250m", "memory", "1536Mi")),
+		bestEffortContainer("proxy"))), cpuMemory)
+	tmpl, err := parseGoTemplate(`{{range .pods}}{{range .containers}}{{.name | upper}} {{lower .class}} ` +
+		`{{quantity "memory" .requests.memory}} {{millicores .requests.cpu}}m {{default "unbounded" .limits.cpu}}{{"\n"}}{{end}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printGoTemplate(&buf, tmpl, report); err != nil {
+		t.Fatal(err)
+	}
+	want := "APP burstable 1.5Gi 250m 1\nPROXY besteffort <none> 0m unbounded\n"
+	if got := buf.String(); got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+}
+
+func TestGoTemplateMissingField(t *testing.T) {
+	report := newReport(toPodData(newPod("default", "web", bestEffortContainer("app"))), cpuMemory)
+	tmpl, err := parseGoTemplate(`{{range .pods}}{{.name}} {{.hpa}} {{default "none" .hpa}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printGoTemplate(&buf, tmpl, report); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "web <no value> none"; got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+}
+
+func TestTemplateDefault(t *testing.T) {
+	for _, tt := range []struct {
+		value, want interface{}
+	}{
+		{nil, "x"},
+		{"", "x"},
+		{0, "x"},
+		{[]interface{}{}, "x"},
+		{map[string]interface{}{}, "x"},
+		{"1", "1"},
+		{false, "x"},
+	} {
+		if got := templateDefault("x", tt.value); got != tt.want {
+			t.Errorf("default x %#v = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+	if _, err := parseGoTemplate("{{.pods"); err == nil {
+		t.Error("parseGoTemplate of an unclosed action = nil error")
+	}
+	if _, err := templateQuantity("cpu", "lots"); err == nil {
+		t.Error("quantity of an invalid value = nil error")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// groupByKeys are the values accepted by --group-by
+var groupByKeys = []string{"pod", "template-hash"}
+
+// printTemplateHashGroups writes a header line per namespace and
+// pod-template-hash with the containers of its pods beneath it, so the old
+// and new ReplicaSets of a rollout can be compared
+func printTemplateHashGroups(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	hash := func(p podqos.PodData) string {
+		return noneIfEmpty(p.Labels[appsv1.DefaultDeploymentUniqueLabelKey])
+	}
+	sorted := append([]podqos.PodData(nil), podData...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].NameSpace != sorted[j].NameSpace {
+			return sorted[i].NameSpace < sorted[j].NameSpace
+		}
+		return hash(sorted[i]) < hash(sorted[j])
+	})
+
+	// align the rows as one table, then put the group lines in between
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(append([]string{"POD NAME"}, containerHeader(opts)...), "\t"))
+	for _, v := range sorted {
+		for _, c := range v.Containers {
+			fmt.Fprintln(tw, strings.Join(append([]string{displayName(v.PodName, opts)}, containerCells(v, c, opts)...), "\t"))
+		}
+	}
+	tw.Flush()
+	lines := strings.Split(buf.String(), "\n")
+
+	if !opts.noHeaders {
+		fmt.Fprintln(w, "  "+lines[0])
+	}
+	next := 1
+	group := ""
+	for _, v := range sorted {
+		if key := displayName(v.NameSpace, opts) + " pod-template-hash=" + hash(v); key != group {
+			fmt.Fprintln(w, key)
+			group = key
+		}
+		for range v.Containers {
+			fmt.Fprintln(w, "  "+lines[next])
+			next++
+		}
+	}
+}
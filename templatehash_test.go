@@ -0,0 +1,72 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// rolloutPods are two pods of the old and one of the new ReplicaSet of web
+func rolloutPods() []*v1.Pod {
+	old1 := newPod("default", "web-5d4f-a", burstableContainer("app"))
+	old2 := newPod("default", "web-5d4f-b", burstableContainer("app"))
+	updated := newPod("default", "web-7c9b-a", guaranteedContainer("app"))
+	for pod, hash := range map[*v1.Pod]string{old1: "5d4f", old2: "5d4f", updated: "7c9b"} {
+		pod.Labels = map[string]string{"pod-template-hash": hash}
+	}
+	return []*v1.Pod{updated, old1, old2}
+}
+
+func TestGroupByTemplateHash(t *testing.T) {
+	pods := append(rolloutPods(), newPod("default", "standalone", bestEffortContainer("app")))
+	var buf bytes.Buffer
+	printTable(&buf, toPodData(pods...), tableOptions{resources: cpuMemory, groupByTemplateHash: true})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	want := []string{
+		"POD NAME CONTAINER CPUl CPUr MEMl MEMr CLASS",
+		"default pod-template-hash=5d4f",
+		"web-5d4f-a app 1 250m 1Gi 128Mi Burstable",
+		"web-5d4f-b app 1 250m 1Gi 128Mi Burstable",
+		"default pod-template-hash=7c9b",
+		"web-7c9b-a app 1 1 1Gi 1Gi Guaranteed",
+		"default pod-template-hash=<none>",
+		"standalone app <none> <none> <none> <none> BestEffort",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("grouped = %q", lines)
+	}
+	for i, line := range lines {
+		if got := strings.Join(strings.Fields(line), " "); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestTemplateHashColumn(t *testing.T) {
+	var buf bytes.Buffer
+	printFlat(&buf, toPodData(rolloutPods()...), tableOptions{resources: cpuMemory, showTemplateHash: true})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "TEMPLATE-HASH") {
+		t.Fatalf("header = %q", lines[0])
+	}
+	for i, want := range []string{"7c9b", "5d4f", "5d4f"} {
+		if fields := strings.Fields(lines[i+1]); fields[len(fields)-1] != want {
+			t.Errorf("row %d = %q, want hash %s", i+1, lines[i+1], want)
+		}
+	}
+}
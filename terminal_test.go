@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestASCIIOnly(t *testing.T) {
+	for in, want := range map[string]string{
+		"web":        "web",
+		"wéb":        "w?b",
+		"web-日本":     "web-??",
+		"tab\there":  "tab?here",
+		"bell\x07":   "bell?",
+		"emoji-🚀-up": "emoji-?-up",
+	} {
+		if got := asciiOnly(in); got != want {
+			t.Errorf("asciiOnly(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTableASCIIOnly(t *testing.T) {
+	podData := toPodData(newPod("default", "café", guaranteedContainer("app")))
+	var buf bytes.Buffer
+	printFlat(&buf, podData, tableOptions{resources: cpuMemory, asciiOnly: true})
+	if got := buf.String(); !strings.Contains(got, "caf?") || strings.Contains(got, "é") {
+		t.Errorf("table = %q, want the pod name as caf?", got)
+	}
+	buf.Reset()
+	printFlat(&buf, podData, tableOptions{resources: cpuMemory})
+	if !strings.Contains(buf.String(), "café") {
+		t.Errorf("table = %q, want the name untouched without --ascii-only", buf.String())
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// maxPlausibleCores is the most cores a threshold without a unit is taken
+// for, anything above is much more likely millicores missing their m
+const maxPlausibleCores = 64
+
+// threshold bounds the effective request of a resource, nil ends are open
+type threshold struct {
+	name     v1.ResourceName
+	min, max *resource.Quantity
+}
+
+// parseThreshold parses a --min/--max value for the resource, rejecting
+// units that don't fit it with a suggestion of what was probably meant
+func parseThreshold(flag string, name v1.ResourceName, value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s %q: %v", flag, value, err)
+	}
+	number := strings.TrimRight(value, "KMGTPEikmgtpe")
+	suffix := value[len(number):]
+	switch name {
+	case v1.ResourceCPU:
+		// the only unit of cpu is m, 500M or 2k would be a huge number
+		// of cores
+		if suffix != "" && suffix != "m" {
+			return nil, fmt.Errorf("invalid --%s %q: cpu is counted in cores or millicores, did you mean %sm", flag, value, number)
+		}
+		if suffix == "" && q.Value() > maxPlausibleCores {
+			return nil, fmt.Errorf("invalid --%s %q: that is %s cores, did you mean %sm", flag, value, value, value)
+		}
+	case v1.ResourceMemory:
+		if suffix == "m" {
+			return nil, fmt.Errorf("invalid --%s %q: m is millibytes, did you mean %sMi", flag, value, number)
+		}
+		// zero is zero in any unit, it asks for pods without a memory request
+		if suffix == "" && q.Value() < 1024 && !q.IsZero() {
+			return nil, fmt.Errorf("invalid --%s %q: that is %s bytes, did you mean %sMi", flag, value, value, value)
+		}
+	}
+	return &q, nil
+}
+
+// within reports whether the pod's effective request of the resource is
+// inside the threshold
+func (t threshold) within(p podqos.PodData) bool {
+	requests, _ := p.EffectiveResources()
+	request := requests.Get(t.name)
+	if t.min != nil && request.Cmp(*t.min) < 0 {
+		return false
+	}
+	return t.max == nil || request.Cmp(*t.max) <= 0
+}
+
+// filterThresholds keeps the pods whose effective requests are within every
+// threshold
+func filterThresholds(podData []podqos.PodData, thresholds []threshold) []podqos.PodData {
+	var kept []podqos.PodData
+	for _, p := range podData {
+		ok := true
+		for _, t := range thresholds {
+			if !t.within(p) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseThreshold(t *testing.T) {
+	for _, tt := range []struct {
+		name  v1.ResourceName
+		value string
+		want  string
+	}{
+		{v1.ResourceCPU, "", ""},
+		{v1.ResourceCPU, "500m", "500m"},
+		{v1.ResourceCPU, "2", "2"},
+		{v1.ResourceCPU, "64", "64"},
+		{v1.ResourceMemory, "128Mi", "128Mi"},
+		{v1.ResourceMemory, "1G", "1G"},
+		{v1.ResourceMemory, "0", "0"},
+		{v1.ResourceMemory, "2048", "2048"},
+	} {
+		q, err := parseThreshold("max-"+string(tt.name), tt.name, tt.value)
+		if err != nil {
+			t.Errorf("parseThreshold(%s, %q) = %v", tt.name, tt.value, err)
+			continue
+		}
+		got := ""
+		if q != nil {
+			got = q.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseThreshold(%s, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseThresholdRejects(t *testing.T) {
+	for _, tt := range []struct {
+		name       v1.ResourceName
+		value      string
+		suggestion string
+	}{
+		{v1.ResourceCPU, "128", "did you mean 128m"},
+		{v1.ResourceCPU, "2Gi", "did you mean 2m"},
+		{v1.ResourceCPU, "500M", "did you mean 500m"},
+		{v1.ResourceCPU, "2k", "did you mean 2m"},
+		{v1.ResourceMemory, "512m", "did you mean 512Mi"},
+		{v1.ResourceMemory, "128", "did you mean 128Mi"},
+		{v1.ResourceMemory, "lots", "quantities must match"},
+	} {
+		_, err := parseThreshold("min-"+string(tt.name), tt.name, tt.value)
+		if err == nil || !strings.Contains(err.Error(), "--min-"+string(tt.name)) || !strings.Contains(err.Error(), tt.suggestion) {
+			t.Errorf("parseThreshold(%s, %q) = %v, want %q", tt.name, tt.value, err, tt.suggestion)
+		}
+	}
+}
+
+func TestFilterThresholds(t *testing.T) {
+	podData := toPodData(
+		newPod("default", "web", burstableContainer("app")),
+		newPod("default", "db", guaranteedContainer("pg")),
+		newPod("default", "batch", bestEffortContainer("job")))
+	zero, err := parseThreshold("max-memory", v1.ResourceMemory, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := podNames(filterThresholds(podData, []threshold{{name: v1.ResourceMemory, max: zero}})); got != "batch" {
+		t.Errorf("--max-memory 0 kept %q, want only the pod without a memory request", got)
+	}
+	min, _ := parseThreshold("min-cpu", v1.ResourceCPU, "500m")
+	if got := podNames(filterThresholds(podData, []threshold{{name: v1.ResourceCPU, min: min}})); got != "db" {
+		t.Errorf("--min-cpu 500m kept %q, want db", got)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+)
+
+// tsvCells replaces tabs and newlines inside the values, a label value with
+// a tab in it would otherwise shift every column after it
+var tsvCells = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// tsvRow joins the cells with single tabs
+func tsvRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = tsvCells.Replace(cell)
+	}
+	return strings.Join(escaped, "\t")
+}
+
+// printTSV writes the flat table with exactly one tab between fields and no
+// padding, for cut and awk
+func printTSV(w io.Writer, podData []podqos.PodData, opts tableOptions) {
+	if !opts.noHeaders {
+		fmt.Fprintln(w, tsvRow(append(podHeader(opts), containerHeader(opts)...)))
+	}
+	for _, v := range podData {
+		for _, c := range v.Containers {
+			fmt.Fprintln(w, tsvRow(append(podCells(v, opts), containerCells(v, c, opts)...)))
+		}
+	}
+}
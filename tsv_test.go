@@ -0,0 +1,59 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintTSV(t *testing.T) {
+	web := newPod("shop", "web", guaranteedContainer("app"), burstableContainer("sidecar"))
+	web.Labels = map[string]string{"team": "front\tend"}
+	podData := toPodData(web, newPod("shop", "db", guaranteedContainer("pg")))
+	opts := tableOptions{resources: cpuMemory, labelColumns: []string{"team"}}
+
+	var buf bytes.Buffer
+	printTSV(&buf, podData, opts)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("printTSV = %q, want a header and three rows", buf.String())
+	}
+	columns := len(strings.Split(lines[0], "\t"))
+	for _, line := range lines {
+		// a tab inside a value would add a column
+		if got := len(strings.Split(line, "\t")); got != columns {
+			t.Errorf("line %q has %d fields, want %d", line, got, columns)
+		}
+		for _, field := range strings.Split(line, "\t") {
+			if field != strings.TrimSpace(field) {
+				t.Errorf("field %q of %q is padded", field, line)
+			}
+		}
+	}
+	if !strings.HasPrefix(lines[0], "NAMESPACE\tPOD NAME\t") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "\tfront end") {
+		t.Errorf("row = %q, want the tab in the label replaced", lines[1])
+	}
+
+	buf.Reset()
+	opts.noHeaders = true
+	printTSV(&buf, podData, opts)
+	if got := strings.Split(buf.String(), "\n"); len(got) != 4 || got[0] != lines[1] {
+		t.Errorf("printTSV with noHeaders = %q, want the rows only", buf.String())
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// annotateUsage sets the Usage of every container from the PodMetrics of the
+// namespace. Without metrics-server the api isn't there, that is a warning
+// and the usage is left empty rather than failing the whole run
+func annotateUsage(ctx context.Context, metrics metricsclientset.Interface, namespace string, podData []podqos.PodData) error {
+	list, err := metrics.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		logger.warnf("the metrics.k8s.io api isn't available, is metrics-server installed? %v", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	usage := map[string]podqos.ResourceData{}
+	for _, pm := range list.Items {
+		for _, c := range pm.Containers {
+			usage[pm.Namespace+"/"+pm.Name+"/"+c.Name] = podqos.ResourceData(c.Usage)
+		}
+	}
+	for i := range podData {
+		for j := range podData[i].Containers {
+			c := &podData[i].Containers[j]
+			c.Usage = usage[podData[i].NameSpace+"/"+podData[i].PodName+"/"+c.Name]
+		}
+	}
+	return nil
+}
+
+// usageOfRequest is the usage as a share of the request, above 100% the
+// container is bursting past what it asked for and its request is likely too
+// low. It is <none> without usage and n/a without a request to compare to
+func usageOfRequest(c podqos.ContainerData, name v1.ResourceName) string {
+	if !c.Usage.Has(name) {
+		return "<none>"
+	}
+	request := c.Requests.Get(name)
+	if request.IsZero() {
+		return "n/a"
+	}
+	percent := fmt.Sprintf("%.0f%%", float64(c.Usage.Get(name).MilliValue())*100/float64(request.MilliValue()))
+	if c.Usage.Get(name).Cmp(*request) > 0 {
+		percent += "!"
+	}
+	return percent
+}
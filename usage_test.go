@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// fakeMetrics serves the PodMetrics
+func fakeMetrics(pods ...metricsv1beta1.PodMetrics) *metricsfake.Clientset {
+	metrics := &metricsfake.Clientset{}
+	metrics.AddReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: pods}, nil
+	})
+	return metrics
+}
+
+// podMetrics is the usage of a single container pod
+func podMetrics(namespace, name, container string, usage v1.ResourceList) metricsv1beta1.PodMetrics {
+	return metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Containers: []metricsv1beta1.ContainerMetrics{{Name: container, Usage: usage}},
+	}
+}
+
+func TestAnnotateUsage(t *testing.T) {
+	metrics := fakeMetrics(podMetrics("default", "web", "app", quantities("cpu", "120m", "memory", "96Mi")))
+	podData := toPodData(newPod("default", "web", burstableContainer("app")), newPod("default", "db", guaranteedContainer("pg")))
+	if err := annotateUsage(context.Background(), metrics, "default", podData); err != nil {
+		t.Fatal(err)
+	}
+	usage := podData[0].Containers[0].Usage
+	if usage.CPU().String() != "120m" || usage.Memory().String() != "96Mi" {
+		t.Errorf("web usage = %v, want 120m 96Mi", usage)
+	}
+	if podData[1].Containers[0].Usage.Has(v1.ResourceCPU) {
+		t.Errorf("db usage = %v, want none without metrics", podData[1].Containers[0].Usage)
+	}
+}
+
+func TestAnnotateUsageWithoutMetricsServer(t *testing.T) {
+	buf := captureLog(t)
+	metrics := &metricsfake.Clientset{}
+	metrics.AddReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(metricsv1beta1.Resource("pods"), "")
+	})
+	podData := toPodData(newPod("default", "web", burstableContainer("app")))
+	if err := annotateUsage(context.Background(), metrics, "default", podData); err != nil {
+		t.Errorf("annotateUsage = %v, want only a warning", err)
+	}
+	if !strings.Contains(buf.String(), "is metrics-server installed?") {
+		t.Errorf("log = %q, want a metrics-server warning", buf.String())
+	}
+}
+
+func TestUsageOfRequest(t *testing.T) {
+	c := podqos.NewContainerData(newContainer("app", nil, quantities("cpu", "200m", "memory", "128Mi")))
+	c.Usage = podqos.ResourceData(quantities("cpu", "300m", "memory", "64Mi"))
+	if got := usageOfRequest(c, v1.ResourceCPU); got != "150%!" {
+		t.Errorf("300m of a 200m request = %q, want 150%%!", got)
+	}
+	if got := usageOfRequest(c, v1.ResourceMemory); got != "50%" {
+		t.Errorf("64Mi of a 128Mi request = %q, want 50%%", got)
+	}
+	noRequest := podqos.NewContainerData(bestEffortContainer("app"))
+	noRequest.Usage = c.Usage
+	if got := usageOfRequest(noRequest, v1.ResourceCPU); got != "n/a" {
+		t.Errorf("usage without a request = %q, want n/a", got)
+	}
+	if got := usageOfRequest(podqos.NewContainerData(bestEffortContainer("app")), v1.ResourceCPU); got != "<none>" {
+		t.Errorf("no usage = %q, want <none>", got)
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+// barWidth is how many cells a fill bar has
+const barWidth = 5
+
+// fillBar draws how much of its limit the container requests, e.g.
+// "[███░░]" for 60%. Without a limit the request can grow unbounded, and a
+// request above the limit is drawn full
+func fillBar(c podqos.ContainerData, name v1.ResourceName, width int) string {
+	if !c.Limits.Has(name) {
+		if !c.Requests.Has(name) {
+			return "<none>"
+		}
+		return "unbounded"
+	}
+	limit := c.Limits.Get(name).MilliValue()
+	request := c.Requests.Get(name).MilliValue()
+	if !c.Requests.Has(name) {
+		// the api server defaults the request to the limit
+		request = limit
+	}
+	filled := width
+	if limit > 0 && request < limit {
+		filled = int((request*int64(width) + limit/2) / limit)
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFillBar(t *testing.T) {
+	tests := []struct {
+		limits, requests v1.ResourceList
+		want             string
+	}{
+		{quantities("cpu", "1"), quantities("cpu", "600m"), "[███░░]"},
+		{quantities("cpu", "1"), quantities("cpu", "1"), "[█████]"},
+		{quantities("cpu", "1"), quantities("cpu", "0"), "[░░░░░]"},
+		// the request defaults to the limit
+		{quantities("cpu", "1"), nil, "[█████]"},
+		{quantities("cpu", "500m"), quantities("cpu", "2"), "[█████]"},
+		{nil, quantities("cpu", "250m"), "unbounded"},
+		{nil, nil, "<none>"},
+	}
+	for _, tt := range tests {
+		c := podqos.NewContainerData(newContainer("app", tt.limits, tt.requests))
+		if got := fillBar(c, v1.ResourceCPU, 5); got != tt.want {
+			t.Errorf("fillBar(%v/%v) = %q, want %q", tt.requests, tt.limits, got, tt.want)
+		}
+	}
+	c := podqos.NewContainerData(burstableContainer("app"))
+	if got := fillBar(c, v1.ResourceMemory, 8); got != "[█░░░░░░░]" {
+		t.Errorf("fillBar of 128Mi/1Gi = %q", got)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// watchOptions controls watch mode
+type watchOptions struct {
+	// resources the class is computed from
+	resources []v1.ResourceName
+	// buffer is how many events can queue up while rows are printed
+	buffer int
+	// podName watches just that pod instead of the whole namespace
+	podName string
+	// eventsLog gets a json line for every row printed, nil logs nothing
+	eventsLog io.Writer
+}
+
+// watchEvent is a line of --events-log
+type watchEvent struct {
+	Time      time.Time           `json:"time"`
+	Event     watch.EventType     `json:"event"`
+	Namespace string              `json:"namespace"`
+	Pod       string              `json:"pod"`
+	Container string              `json:"container"`
+	Class     podqos.PodQosPolicy `json:"class"`
+}
+
+// listOptions scopes the list and watch to the named pod, if any
+func (o watchOptions) listOptions(resourceVersion string) metav1.ListOptions {
+	opts := metav1.ListOptions{ResourceVersion: resourceVersion}
+	if o.podName != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", o.podName).String()
+	}
+	return opts
+}
+
+// classWatcher remembers the last class seen for every container and prints
+// a row whenever it changes
+type classWatcher struct {
+	tw      *tabwriter.Writer
+	opts    watchOptions
+	classes map[string]podqos.PodQosPolicy
+	// changes counts class changes of containers that were already known
+	changes int
+}
+
+func newClassWatcher(w io.Writer, opts watchOptions) *classWatcher {
+	cw := &classWatcher{
+		tw:      tabwriter.NewWriter(w, 0, 0, 2, ' ', 0),
+		opts:    opts,
+		classes: map[string]podqos.PodQosPolicy{},
+	}
+	fmt.Fprintln(cw.tw, strings.Join([]string{"EVENT", "NAMESPACE", "POD NAME", "CONTAINER", "CLASS"}, "\t"))
+	cw.tw.Flush()
+	return cw
+}
+
+// handle prints the containers of the pod in the event whose class is new
+// or changed, deleted pods are printed once and forgotten
+func (cw *classWatcher) handle(event watch.Event) {
+	pod, ok := event.Object.(*v1.Pod)
+	if !ok {
+		return
+	}
+	data := podqos.NewPodData(*pod)
+	for _, c := range data.Containers {
+		key := data.NameSpace + "/" + data.PodName + "/" + c.Name
+		class := c.QosClass(cw.opts.resources)
+		if event.Type == watch.Deleted {
+			delete(cw.classes, key)
+		} else {
+			old, seen := cw.classes[key]
+			if seen && old == class {
+				continue
+			}
+			if seen {
+				cw.changes++
+			}
+			cw.classes[key] = class
+		}
+		fmt.Fprintln(cw.tw, strings.Join([]string{string(event.Type), data.NameSpace, data.PodName, c.Name, string(class)}, "\t"))
+		cw.log(watchEvent{Time: time.Now().UTC(), Event: event.Type, Namespace: data.NameSpace, Pod: data.PodName, Container: c.Name, Class: class})
+	}
+	cw.tw.Flush()
+}
+
+// log writes the event to the events log, each line in a single write so
+// the file is complete up to the last event even when watch is killed
+func (cw *classWatcher) log(event watchEvent) {
+	if cw.opts.eventsLog == nil {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err == nil {
+		_, err = cw.opts.eventsLog.Write(append(line, '\n'))
+	}
+	if err != nil {
+		logger.warnf("failed to write the events log: %v", err)
+	}
+}
+
+// relist lists the pods and feeds them through the watcher as ADDED
+// events, so only containers whose class changed in the meantime are
+// printed. It returns the resourceVersion to watch from
+func (cw *classWatcher) relist(ctx context.Context, clientset kubernetes.Interface, namespace string) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, cw.opts.listOptions(""))
+	if err != nil {
+		return "", err
+	}
+	for i := range pods.Items {
+		cw.handle(watch.Event{Type: watch.Added, Object: &pods.Items[i]})
+	}
+	return pods.ResourceVersion, nil
+}
+
+// follow handles the events of one watch until it ends. Events are read
+// into a buffered channel so a burst doesn't block the api stream while
+// rows are printed. It returns the last resourceVersion seen and gone when
+// that version is too old to resume from, a 410 from the api
+func (cw *classWatcher) follow(ctx context.Context, watcher watch.Interface, resourceVersion string) (string, bool, error) {
+	defer watcher.Stop()
+	size := cw.opts.buffer
+	if size < 0 {
+		size = 0
+	}
+	events := make(chan watch.Event, size)
+	go func() {
+		defer close(events)
+		for event := range watcher.ResultChan() {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, false, nil
+		case event, ok := <-events:
+			if !ok {
+				return resourceVersion, false, nil
+			}
+			if event.Type == watch.Error {
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return "", true, nil
+				}
+				return resourceVersion, false, err
+			}
+			if pod, ok := event.Object.(*v1.Pod); ok {
+				resourceVersion = pod.ResourceVersion
+			}
+			cw.handle(event)
+		}
+	}
+}
+
+// watchPods prints the pods, or the single pod named in the options, then
+// follows them until the context is done. A watch the server closes is
+// resumed from the last resourceVersion, and when that is gone the pods are
+// listed again. It returns how many class changes were observed
+func watchPods(ctx context.Context, w io.Writer, clientset kubernetes.Interface, namespace string, opts watchOptions) (int, error) {
+	cw := newClassWatcher(w, opts)
+	resourceVersion, err := cw.relist(ctx, clientset, namespace)
+	if err != nil {
+		return 0, err
+	}
+	for ctx.Err() == nil {
+		watcher, err := clientset.CoreV1().Pods(namespace).Watch(ctx, opts.listOptions(resourceVersion))
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return cw.changes, err
+		}
+		var gone bool
+		resourceVersion, gone, err = cw.follow(ctx, watcher, resourceVersion)
+		if err != nil {
+			return cw.changes, err
+		}
+		if gone {
+			if resourceVersion, err = cw.relist(ctx, clientset, namespace); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return cw.changes, err
+			}
+		}
+	}
+	return cw.changes, nil
+}
+
+// waitForClass watches the pod until it has the class, it returns nil as
+// soon as it does and an error once the context times out or is cancelled
+// by Ctrl-C. A pod that doesn't exist yet is waited for as well
+func waitForClass(ctx context.Context, clientset kubernetes.Interface, namespace, name string, class podqos.PodQosPolicy, resources []v1.ResourceName) error {
+	opts := watchOptions{podName: name}
+	reached := func(pod *v1.Pod) bool {
+		data := podqos.NewPodData(*pod)
+		return data.QosClass(resources) == class
+	}
+	for {
+		reachedNow, err := waitOnce(ctx, clientset, namespace, opts, reached)
+		if reachedNow {
+			return nil
+		}
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("interrupted waiting for pod %s to become %s", name, class)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out waiting for pod %s to become %s", name, class)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// waitOnce lists the pod and follows a single watch of it, it returns
+// whether reached was true for the pod before the watch ended
+func waitOnce(ctx context.Context, clientset kubernetes.Interface, namespace string, opts watchOptions, reached func(*v1.Pod) bool) (bool, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, opts.listOptions(""))
+	if err != nil {
+		return false, err
+	}
+	for i := range pods.Items {
+		if reached(&pods.Items[i]) {
+			return true, nil
+		}
+	}
+	watcher, err := clientset.CoreV1().Pods(namespace).Watch(ctx, opts.listOptions(pods.ResourceVersion))
+	if err != nil {
+		return false, err
+	}
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case event, ok := <-watcher.ResultChan():
+			// a closed watch or a gone resourceVersion lists again
+			if !ok || event.Type == watch.Error {
+				return false, nil
+			}
+			if pod, ok := event.Object.(*v1.Pod); ok && event.Type != watch.Deleted && reached(pod) {
+				return true, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 Jeff d'Ambly
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jdambly/kubectl-podqos/pkg/podqos"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeWatch has the clientset's pod watches return the same fake watcher
+func fakeWatch(clientset *fake.Clientset) *watch.FakeWatcher {
+	watcher := watch.NewFake()
+	clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+	return watcher
+}
+
+func TestWatchPodsStopsAtTimeout(t *testing.T) {
+	web := newPod("default", "web", bestEffortContainer("app"))
+	clientset := fake.NewSimpleClientset(web)
+	watcher := fakeWatch(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		// the api server opens the watch with an ADDED event per pod
+		watcher.Add(web)
+		resized := newPod("default", "web", guaranteedContainer("app"))
+		watcher.Modify(resized)
+		// an update that keeps the class isn't a change
+		watcher.Modify(resized)
+	}()
+
+	var buf bytes.Buffer
+	changes, err := watchPods(ctx, &buf, clientset, "default", watchOptions{resources: cpuMemory})
+	if err != nil {
+		t.Fatalf("watchPods = %v, want a clean exit at the timeout", err)
+	}
+	if changes != 1 {
+		t.Errorf("changes = %d, want 1", changes)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var rows []string
+	for _, line := range lines[1:] {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	if strings.Join(rows, "\n") != "ADDED default web app BestEffort\nMODIFIED default web app Guaranteed" {
+		t.Errorf("rows = %q", rows)
+	}
+}
+
+func TestWatchEventsLog(t *testing.T) {
+	web := newPod("default", "web", bestEffortContainer("app"))
+	clientset := fake.NewSimpleClientset(web)
+	watcher := fakeWatch(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		watcher.Modify(newPod("default", "web", guaranteedContainer("app")))
+		watcher.Delete(web)
+	}()
+
+	// opened the way --events-log does, the earlier lines are kept
+	path := filepath.Join(t.TempDir(), "events.log")
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if _, err := watchPods(ctx, &buf, clientset, "default", watchOptions{resources: cpuMemory, buffer: 10, eventsLog: f}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "{}" {
+		t.Fatalf("events log = %q, want the earlier line and three events", content)
+	}
+	var events []string
+	for _, line := range lines[1:] {
+		var event watchEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("line %q has no time", line)
+		}
+		events = append(events, strings.Join([]string{string(event.Event), event.Namespace, event.Pod, event.Container, string(event.Class)}, " "))
+	}
+	if got := strings.Join(events, "\n"); got != "ADDED default web app BestEffort\nMODIFIED default web app Guaranteed\nDELETED default web app BestEffort" {
+		t.Errorf("events = %q", events)
+	}
+}
+
+func TestWatchPodsRelistsWhenGone(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", bestEffortContainer("app")))
+	var lists int64
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt64(&lists, 1)
+		return false, nil, nil
+	})
+	first, second := watch.NewFake(), watch.NewFake()
+	watchers := make(chan watch.Interface, 2)
+	watchers <- first
+	watchers <- second
+	clientset.PrependWatchReactor("pods", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, <-watchers, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// the pod changes while the watch is gone, the relist has to catch it
+		resized := newPod("default", "web", guaranteedContainer("app"))
+		if err := clientset.Tracker().Update(v1.SchemeGroupVersion.WithResource("pods"), resized, "default"); err != nil {
+			t.Error(err)
+		}
+		expired := apierrors.NewResourceExpired("too old resource version: 1 (2)").ErrStatus
+		first.Error(&expired)
+		second.Modify(newPod("default", "web", burstableContainer("app")))
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	var buf bytes.Buffer
+	changes, err := watchPods(ctx, &buf, clientset, "default", watchOptions{resources: cpuMemory})
+	if err != nil {
+		t.Fatalf("watchPods = %v, want the 410 handled", err)
+	}
+	if lists != 2 {
+		t.Errorf("listed %d times, want a relist after the 410", lists)
+	}
+	if changes != 2 {
+		t.Errorf("changes = %d, want 2", changes)
+	}
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := "ADDED default web app BestEffort\nADDED default web app Guaranteed\nMODIFIED default web app Burstable"
+	if strings.Join(rows, "\n") != want {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestWatchPodsFailsOnOtherErrors(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	watcher := fakeWatch(clientset)
+	go func() {
+		forbidden := apierrors.NewForbidden(v1.Resource("pods"), "", nil).ErrStatus
+		watcher.Error(&forbidden)
+	}()
+	var buf bytes.Buffer
+	if _, err := watchPods(context.Background(), &buf, clientset, "default", watchOptions{resources: cpuMemory}); !apierrors.IsForbidden(err) {
+		t.Errorf("watchPods = %v, want the forbidden error", err)
+	}
+}
+
+func TestWatchPodsScopedToOnePod(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", bestEffortContainer("app")))
+	watcher := fakeWatch(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go watcher.Modify(newPod("default", "web", guaranteedContainer("app")))
+
+	var buf bytes.Buffer
+	if _, err := watchPods(ctx, &buf, clientset, "default", watchOptions{resources: cpuMemory, podName: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	var verbs []string
+	for _, action := range clientset.Actions() {
+		var selector string
+		switch a := action.(type) {
+		case k8stesting.ListAction:
+			selector = a.GetListRestrictions().Fields.String()
+		case k8stesting.WatchAction:
+			selector = a.GetWatchRestrictions().Fields.String()
+		default:
+			continue
+		}
+		verbs = append(verbs, action.GetVerb())
+		if selector != "metadata.name=web" {
+			t.Errorf("%s field selector = %q, want metadata.name=web", action.GetVerb(), selector)
+		}
+	}
+	if strings.Join(verbs, " ") != "list watch" {
+		t.Errorf("actions = %v, want a list and a watch", verbs)
+	}
+	if !strings.Contains(buf.String(), "MODIFIED") {
+		t.Errorf("watch = %q, want the class change of web", buf.String())
+	}
+}
+
+func TestWaitForClass(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", burstableContainer("app")))
+	watcher := fakeWatch(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		watcher.Modify(newPod("default", "web", bestEffortContainer("app")))
+		watcher.Modify(newPod("default", "web", guaranteedContainer("app")))
+	}()
+	if err := waitForClass(ctx, clientset, "default", "web", podqos.Guaranteed, cpuMemory); err != nil {
+		t.Errorf("waitForClass = %v, want the resize to Guaranteed", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("waitForClass only returned at the timeout")
+	}
+
+	// a pod that already has the class doesn't wait at all
+	if err := waitForClass(context.Background(), clientset, "default", "web", podqos.Burstable, cpuMemory); err != nil {
+		t.Errorf("waitForClass of the current class = %v", err)
+	}
+}
+
+func TestWaitForClassTimesOut(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", burstableContainer("app")))
+	fakeWatch(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := waitForClass(ctx, clientset, "default", "web", podqos.Guaranteed, cpuMemory)
+	if err == nil || !strings.Contains(err.Error(), "timed out waiting for pod web to become Guaranteed") {
+		t.Errorf("waitForClass = %v, want a timeout", err)
+	}
+}